@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/gg-mike/ccli/pkg/agent"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Build host agent connecting outbound to the server",
+	Run: func(cmd *cobra.Command, args []string) {
+		labels := map[string]string{}
+		for _, label := range viper.GetStringSlice(AGENT_LABELS) {
+			k, v, _ := strings.Cut(label, "=")
+			labels[k] = v
+		}
+
+		config := agent.Config{
+			Server:   viper.GetString(AGENT_SERVER),
+			Token:    viper.GetString(AGENT_TOKEN),
+			Name:     viper.GetString(AGENT_NAME),
+			System:   viper.GetString(AGENT_SYSTEM),
+			Pool:     viper.GetString(AGENT_POOL),
+			Labels:   labels,
+			Capacity: viper.GetInt(AGENT_CAPACITY),
+			Shell:    viper.GetString(AGENT_SHELL),
+			State:    viper.GetString(AGENT_STATE),
+		}
+
+		client := agent.NewClient(logger, config)
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			client.Shutdown()
+		}()
+
+		if err := client.Run(); err != nil {
+			logger.Fatal().Err(err).Msg("agent ended with error")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+
+	hostname, _ := os.Hostname()
+
+	agentCmd.Flags().String(AGENT_SERVER, "", "base URL of the ccli server")
+	agentCmd.MarkFlagRequired(AGENT_SERVER)
+	agentCmd.Flags().String(AGENT_TOKEN, "", "one-time registration token (only for the first run)")
+	agentCmd.Flags().String(AGENT_NAME, hostname, "worker name to register with")
+	agentCmd.Flags().String(AGENT_SYSTEM, runtime.GOOS, "system advertised to pipelines")
+	agentCmd.Flags().String(AGENT_POOL, "", "worker pool (registration token pool if empty)")
+	agentCmd.Flags().StringSlice(AGENT_LABELS, []string{}, "labels advertised to pipelines (key=value)")
+	agentCmd.Flags().Int(AGENT_CAPACITY, 1, "number of builds run at once")
+	agentCmd.Flags().String(AGENT_SHELL, "sh", "shell running build commands")
+	agentCmd.Flags().String(AGENT_STATE, "ccli-agent.json", "file storing credentials issued on registration")
+	agentCmd.MarkFlagFilename(AGENT_STATE)
+}
@@ -4,17 +4,13 @@ import (
 	"errors"
 	"slices"
 
+	"github.com/gg-mike/ccli/pkg/kubernetes"
+	"github.com/gg-mike/ccli/pkg/model"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-func validateScheduler(cmd *cobra.Command) error {
-	scheduler := viper.GetString(SCHEDULER)
-	if !slices.Contains([]string{"standalone", "k8s"}, scheduler) {
-		logger.Error().Msgf("flag '%s' has invalid value [%s]", SCHEDULER, scheduler)
-		cmd.Help()
-		return errors.New("invalid")
-	}
+func validateCluster(cmd *cobra.Command) error {
 	mode := viper.GetString(K8S_MODE)
 	if !slices.Contains([]string{"inner", "outer"}, mode) {
 		logger.Error().Msgf("flag '%s' has invalid value [%s]", K8S_MODE, mode)
@@ -24,10 +20,28 @@ func validateScheduler(cmd *cobra.Command) error {
 	return nil
 }
 
-func addSchedulerFlag(cmd *cobra.Command) {
+// clusterConfig returns the cluster given by k8s flags, nil when none of them
+// is set.
+func clusterConfig() *model.ClusterConfig {
+	for _, key := range []string{K8S_MODE, K8S_CONFIG, K8S_NAMESPACE, K8S_BUILDER_IMAGE, K8S_CAPACITY} {
+		if viper.IsSet(key) {
+			return &model.ClusterConfig{
+				Mode:         viper.GetString(K8S_MODE),
+				Namespace:    viper.GetString(K8S_NAMESPACE),
+				BuilderImage: viper.GetString(K8S_BUILDER_IMAGE),
+			}
+		}
+	}
+	return nil
+}
+
+func addClusterFlags(cmd *cobra.Command) {
 	cmd.Flags().String(SCHEDULER, "standalone", "scheduler type (standalone or k8s)")
-	cmd.Flags().String(K8S_MODE, "outer", "mode of work, in/out-cluster (inner or outer)")
-	cmd.Flags().String(K8S_CONFIG, "", "k8s config filepath (else default location is used)")
+	cmd.Flags().MarkDeprecated(SCHEDULER, "the cluster is registered as worker whenever k8s flags are set")
+	cmd.Flags().String(K8S_MODE, "outer", "mode of work, in/out-cluster (inner or outer), setting any k8s flag registers the cluster as worker")
+	cmd.Flags().String(K8S_CONFIG, "", "k8s config filepath (else default location is used), read on start and kept in vault")
 	cmd.MarkFlagFilename(K8S_CONFIG)
 	cmd.Flags().String(K8S_NAMESPACE, "default", "namespace were worker pods will be located")
+	cmd.Flags().String(K8S_BUILDER_IMAGE, kubernetes.DefaultBuilderImage, "kaniko image used by image_build steps")
+	cmd.Flags().Int(K8S_CAPACITY, 100, "concurrent builds in the cluster")
 }
@@ -1,12 +1,53 @@
 package cmd
 
 var (
-	ADDRESS       = "address"
-	DB_URL        = "db.url"
-	VAULT_TOKEN   = "vault.token"
-	VAULT_URL     = "vault.url"
-	SCHEDULER     = "scheduler"
-	K8S_MODE      = "k8s.mode"
-	K8S_CONFIG    = "k8s.config"
-	K8S_NAMESPACE = "k8s.namespace"
+	ADDRESS           = "address"
+	DB_URL            = "db.url"
+	VAULT_TOKEN       = "vault.token"
+	VAULT_URL         = "vault.url"
+	SCHEDULER         = "scheduler"
+	K8S_MODE          = "k8s.mode"
+	K8S_CONFIG        = "k8s.config"
+	K8S_NAMESPACE     = "k8s.namespace"
+	K8S_BUILDER_IMAGE = "k8s.builder-image"
+	K8S_CAPACITY      = "k8s.capacity"
+
+	INSTANCE           = "instance"
+	RECONCILE_INTERVAL = "reconcile.interval"
+
+	HEALTH_INTERVAL           = "health.interval"
+	HEALTH_FAILURE_THRESHOLD  = "health.failure-threshold"
+	HEALTH_RECOVERY_THRESHOLD = "health.recovery-threshold"
+
+	AUTOSCALE_POOL                = "autoscale.pool"
+	AUTOSCALE_MIN                 = "autoscale.min"
+	AUTOSCALE_MAX                 = "autoscale.max"
+	AUTOSCALE_CAPACITY            = "autoscale.capacity"
+	AUTOSCALE_INTERVAL            = "autoscale.interval"
+	AUTOSCALE_SCALE_UP_WAIT       = "autoscale.scale-up-wait"
+	AUTOSCALE_SCALE_UP_COOLDOWN   = "autoscale.scale-up-cooldown"
+	AUTOSCALE_SCALE_DOWN_COOLDOWN = "autoscale.scale-down-cooldown"
+	AUTOSCALE_IDLE_TIMEOUT        = "autoscale.idle-timeout"
+	AUTOSCALE_DIND_HOST           = "autoscale.dind.host"
+	AUTOSCALE_DIND_IMAGE          = "autoscale.dind.image"
+	AUTOSCALE_DIND_NETWORK        = "autoscale.dind.network"
+
+	AUTH_ENABLED            = "auth.enabled"
+	AUTH_ROOT_TOKEN         = "auth.root-token"
+	AUTH_ADMINS             = "auth.admins"
+	AUTH_OIDC_ISSUER        = "auth.oidc.issuer"
+	AUTH_OIDC_CLIENT_ID     = "auth.oidc.client-id"
+	AUTH_OIDC_CLIENT_SECRET = "auth.oidc.client-secret"
+	AUTH_OIDC_REDIRECT_URL  = "auth.oidc.redirect-url"
+	AUTH_OIDC_TOKEN_TTL     = "auth.oidc.token-ttl"
+
+	AGENT_SERVER   = "agent.server"
+	AGENT_TOKEN    = "agent.token"
+	AGENT_NAME     = "agent.name"
+	AGENT_SYSTEM   = "agent.system"
+	AGENT_POOL     = "agent.pool"
+	AGENT_LABELS   = "agent.labels"
+	AGENT_CAPACITY = "agent.capacity"
+	AGENT_SHELL    = "agent.shell"
+	AGENT_STATE    = "agent.state"
 )
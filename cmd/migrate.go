@@ -10,13 +10,8 @@ var migrateCmd = &cobra.Command{
 	Use:   "migrate",
 	Short: "Migrate model schemas to database",
 	Run: func(cmd *cobra.Command, args []string) {
-		if validateScheduler(cmd) != nil {
-			return
-		}
-
 		flags := migrate.Flags{
-			DbUrl:     viper.GetString(DB_URL),
-			Scheduler: viper.GetString(SCHEDULER),
+			DbUrl: viper.GetString(DB_URL),
 		}
 
 		handler := migrate.NewHandler(logger, &flags)
@@ -32,6 +27,4 @@ func init() {
 
 	migrateCmd.Flags().String(DB_URL, "", "database connection URL")
 	migrateCmd.MarkFlagRequired(DB_URL)
-
-	addSchedulerFlag(migrateCmd)
 }
@@ -1,7 +1,13 @@
 package cmd
 
 import (
-	"github.com/gg-mike/ccli/pkg/engine/k8s"
+	"os"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/auth"
+	"github.com/gg-mike/ccli/pkg/autoscale"
+	"github.com/gg-mike/ccli/pkg/engine"
+	"github.com/gg-mike/ccli/pkg/health"
 	"github.com/gg-mike/ccli/pkg/serve"
 	"github.com/gg-mike/ccli/pkg/vault"
 	"github.com/spf13/cobra"
@@ -12,7 +18,7 @@ var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "HTTP CI/CD server",
 	Run: func(cmd *cobra.Command, args []string) {
-		if validateScheduler(cmd) != nil {
+		if validateCluster(cmd) != nil {
 			return
 		}
 
@@ -23,11 +29,45 @@ var serveCmd = &cobra.Command{
 				Url:   viper.GetString(VAULT_URL),
 				Token: viper.GetString(VAULT_TOKEN),
 			},
-			Scheduler: viper.GetString(SCHEDULER),
-			K8s: k8s.Config{
-				Mode:      viper.GetString(K8S_MODE),
-				Config:    viper.GetString(K8S_CONFIG),
-				Namespace: viper.GetString(K8S_NAMESPACE),
+			Engine: engine.Config{
+				Instance:          viper.GetString(INSTANCE),
+				ReconcileInterval: viper.GetDuration(RECONCILE_INTERVAL),
+			},
+			K8s:           clusterConfig(),
+			K8sKubeconfig: viper.GetString(K8S_CONFIG),
+			K8sCapacity:   viper.GetInt(K8S_CAPACITY),
+			Health: health.Config{
+				Interval:          viper.GetDuration(HEALTH_INTERVAL),
+				FailureThreshold:  viper.GetInt(HEALTH_FAILURE_THRESHOLD),
+				RecoveryThreshold: viper.GetInt(HEALTH_RECOVERY_THRESHOLD),
+			},
+			Autoscale: autoscale.Config{
+				Pool:              viper.GetString(AUTOSCALE_POOL),
+				Min:               viper.GetInt(AUTOSCALE_MIN),
+				Max:               viper.GetInt(AUTOSCALE_MAX),
+				Capacity:          viper.GetInt(AUTOSCALE_CAPACITY),
+				Interval:          viper.GetDuration(AUTOSCALE_INTERVAL),
+				ScaleUpWait:       viper.GetDuration(AUTOSCALE_SCALE_UP_WAIT),
+				ScaleUpCooldown:   viper.GetDuration(AUTOSCALE_SCALE_UP_COOLDOWN),
+				ScaleDownCooldown: viper.GetDuration(AUTOSCALE_SCALE_DOWN_COOLDOWN),
+				IdleTimeout:       viper.GetDuration(AUTOSCALE_IDLE_TIMEOUT),
+			},
+			Dind: autoscale.DockerProvisioner{
+				Host:    viper.GetString(AUTOSCALE_DIND_HOST),
+				Image:   viper.GetString(AUTOSCALE_DIND_IMAGE),
+				Network: viper.GetString(AUTOSCALE_DIND_NETWORK),
+			},
+			Auth: auth.Config{
+				Enabled:   viper.GetBool(AUTH_ENABLED),
+				RootToken: viper.GetString(AUTH_ROOT_TOKEN),
+				Admins:    viper.GetStringSlice(AUTH_ADMINS),
+				OIDC: auth.OIDCConfig{
+					Issuer:       viper.GetString(AUTH_OIDC_ISSUER),
+					ClientID:     viper.GetString(AUTH_OIDC_CLIENT_ID),
+					ClientSecret: viper.GetString(AUTH_OIDC_CLIENT_SECRET),
+					RedirectURL:  viper.GetString(AUTH_OIDC_REDIRECT_URL),
+					TokenTTL:     viper.GetDuration(AUTH_OIDC_TOKEN_TTL),
+				},
 			},
 		}
 
@@ -50,5 +90,35 @@ func init() {
 	serveCmd.Flags().String(VAULT_URL, "", "vault connection URL")
 	serveCmd.MarkFlagRequired(VAULT_URL)
 
-	addSchedulerFlag(serveCmd)
+	hostname, _ := os.Hostname()
+	serveCmd.Flags().String(INSTANCE, hostname, "server instance name, builds running under other instances are failed as orphaned")
+	serveCmd.Flags().Duration(RECONCILE_INTERVAL, 5*time.Minute, "interval of orphaned builds and resources cleanup (0 runs it only on startup)")
+
+	serveCmd.Flags().Duration(HEALTH_INTERVAL, 30*time.Second, "interval of worker health checks (0 disables them)")
+	serveCmd.Flags().Int(HEALTH_FAILURE_THRESHOLD, 3, "consecutive failed checks before worker is marked unreachable")
+	serveCmd.Flags().Int(HEALTH_RECOVERY_THRESHOLD, 2, "consecutive successful checks before unreachable worker is used again")
+
+	serveCmd.Flags().String(AUTOSCALE_POOL, "autoscale", "pool of ephemeral Docker workers")
+	serveCmd.Flags().Int(AUTOSCALE_MIN, 0, "minimal number of ephemeral workers")
+	serveCmd.Flags().Int(AUTOSCALE_MAX, 0, "maximal number of ephemeral workers (0 disables autoscaling)")
+	serveCmd.Flags().Int(AUTOSCALE_CAPACITY, 2, "capacity of every ephemeral worker")
+	serveCmd.Flags().Duration(AUTOSCALE_INTERVAL, 15*time.Second, "interval of autoscaling decisions")
+	serveCmd.Flags().Duration(AUTOSCALE_SCALE_UP_WAIT, 30*time.Second, "queue wait time triggering scale up even with free slots")
+	serveCmd.Flags().Duration(AUTOSCALE_SCALE_UP_COOLDOWN, time.Minute, "minimal time between scale ups")
+	serveCmd.Flags().Duration(AUTOSCALE_SCALE_DOWN_COOLDOWN, 2*time.Minute, "minimal time between scale downs")
+	serveCmd.Flags().Duration(AUTOSCALE_IDLE_TIMEOUT, 5*time.Minute, "idle time after which ephemeral worker is terminated")
+	serveCmd.Flags().String(AUTOSCALE_DIND_HOST, "unix:///var/run/docker.sock", "Docker host running Docker-in-Docker workers")
+	serveCmd.Flags().String(AUTOSCALE_DIND_IMAGE, "docker:dind", "Docker-in-Docker image (must generate TLS certificates into DOCKER_TLS_CERTDIR)")
+	serveCmd.Flags().String(AUTOSCALE_DIND_NETWORK, "", "Docker network shared by server and workers")
+
+	serveCmd.Flags().Bool(AUTH_ENABLED, true, "require authentication for API requests")
+	serveCmd.Flags().String(AUTH_ROOT_TOKEN, "", "static token granting full access (bootstrap)")
+	serveCmd.Flags().StringSlice(AUTH_ADMINS, []string{}, "OIDC subjects or verified emails granted global admin role on login")
+	serveCmd.Flags().String(AUTH_OIDC_ISSUER, "", "OIDC issuer URL (login disabled if empty)")
+	serveCmd.Flags().String(AUTH_OIDC_CLIENT_ID, "", "OIDC client ID")
+	serveCmd.Flags().String(AUTH_OIDC_CLIENT_SECRET, "", "OIDC client secret")
+	serveCmd.Flags().String(AUTH_OIDC_REDIRECT_URL, "", "OIDC redirect URL (pointing to /api/auth/callback)")
+	serveCmd.Flags().Duration(AUTH_OIDC_TOKEN_TTL, 24*time.Hour, "lifetime of tokens issued on OIDC login")
+
+	addClusterFlags(serveCmd)
 }
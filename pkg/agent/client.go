@@ -0,0 +1,242 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/log"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+type Config struct {
+	// Server is the base URL of the ccli server (e.g. https://ci.example.com).
+	Server   string
+	Token    string
+	Name     string
+	System   string
+	Pool     string
+	Labels   map[string]string
+	Capacity int
+	Shell    string
+	// State is the file keeping credentials issued on registration.
+	State string
+}
+
+// Registration is sent once with the one-time token to create the worker.
+type Registration struct {
+	Token    string            `json:"token"`
+	Name     string            `json:"name"`
+	System   string            `json:"system"`
+	Pool     string            `json:"pool"`
+	Labels   map[string]string `json:"labels"`
+	Capacity int               `json:"capacity"`
+}
+
+// Credentials authenticate the agent on every later connection.
+type Credentials struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// Client is the build host side of the agent, it keeps the outbound control
+// connection open and runs shell sessions requested by the server.
+type Client struct {
+	config Config
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan any
+
+	logger log.Logger
+}
+
+func NewClient(logger log.Logger, config Config) *Client {
+	if config.Shell == "" {
+		config.Shell = "sh"
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{
+		config: config,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan any),
+
+		logger: logger.NewComponentLogger("agent"),
+	}
+}
+
+func (c *Client) Run() error {
+	defer close(c.done)
+
+	credentials, err := c.credentials()
+	if err != nil {
+		return err
+	}
+
+	backoff := minBackoff
+	for {
+		start := time.Now()
+		err := c.connect(credentials)
+		if c.ctx.Err() != nil {
+			c.logger.Info().Msg("agent shutdown")
+			return nil
+		}
+		if time.Since(start) > maxBackoff {
+			backoff = minBackoff
+		}
+		c.logger.Warn().Err(err).Str("retry_in", backoff.String()).Msg("connection to server lost")
+		select {
+		case <-time.After(backoff):
+		case <-c.ctx.Done():
+			c.logger.Info().Msg("agent shutdown")
+			return nil
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+func (c *Client) Shutdown() chan any {
+	c.cancel()
+	return c.done
+}
+
+// credentials loads the state saved by previous run or registers the agent
+// using the one-time token.
+func (c *Client) credentials() (Credentials, error) {
+	var credentials Credentials
+	data, err := os.ReadFile(c.config.State)
+	if err == nil {
+		if err := json.Unmarshal(data, &credentials); err != nil {
+			return Credentials{}, fmt.Errorf("invalid agent state [%s]: %v", c.config.State, err)
+		}
+		return credentials, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return Credentials{}, err
+	}
+	if c.config.Token == "" {
+		return Credentials{}, errors.New("agent is not registered and no registration token was given")
+	}
+
+	body, _ := json.Marshal(Registration{
+		Token:    c.config.Token,
+		Name:     c.config.Name,
+		System:   c.config.System,
+		Pool:     c.config.Pool,
+		Labels:   c.config.Labels,
+		Capacity: c.config.Capacity,
+	})
+	res, err := http.Post(strings.TrimSuffix(c.config.Server, "/")+"/api/agents/register", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		var msg bytes.Buffer
+		msg.ReadFrom(res.Body)
+		return Credentials{}, fmt.Errorf("registration failed with status %d: %s", res.StatusCode, msg.String())
+	}
+	if err := json.NewDecoder(res.Body).Decode(&credentials); err != nil {
+		return Credentials{}, err
+	}
+
+	data, _ = json.Marshal(credentials)
+	if err := os.WriteFile(c.config.State, data, 0600); err != nil {
+		return Credentials{}, err
+	}
+	c.logger.Info().Str("name", credentials.Name).Msg("agent registered")
+	return credentials, nil
+}
+
+func (c *Client) connect(credentials Credentials) error {
+	conn, err := c.dial("/api/agents/connect", credentials)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	go func() {
+		<-c.ctx.Done()
+		conn.Close()
+	}()
+
+	if err := conn.WriteJSON(Message{
+		Type:     MessageHello,
+		System:   c.config.System,
+		Labels:   c.config.Labels,
+		Capacity: c.config.Capacity,
+	}); err != nil {
+		return err
+	}
+	c.logger.Info().Str("name", credentials.Name).Msg("connected to server")
+
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(pongTimeout))
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(writeTimeout))
+	})
+	for {
+		conn.SetReadDeadline(time.Now().Add(pongTimeout))
+		var m Message
+		if err := conn.ReadJSON(&m); err != nil {
+			return err
+		}
+		switch m.Type {
+		case MessageSession:
+			go c.session(credentials, m.Session)
+		default:
+			c.logger.Warn().Str("type", m.Type).Msg("unknown message from server")
+		}
+	}
+}
+
+// session runs the shell with its standard streams attached to the session
+// connection until either side closes it.
+func (c *Client) session(credentials Credentials, id string) {
+	conn, err := c.dial("/api/agents/sessions/"+id, credentials)
+	if err != nil {
+		c.logger.Error().Str("session", id).Err(err).Msg("could not open session")
+		return
+	}
+	s := newStream(conn)
+	defer s.Close()
+
+	c.logger.Debug().Str("session", id).Msg("session opened")
+	cmd := exec.CommandContext(c.ctx, c.config.Shell)
+	cmd.Stdin = s
+	cmd.Stdout = s
+	cmd.Stderr = s
+	if err := cmd.Run(); err != nil {
+		c.logger.Warn().Str("session", id).Err(err).Msg("session ended with error")
+		return
+	}
+	c.logger.Debug().Str("session", id).Msg("session closed")
+}
+
+func (c *Client) dial(path string, credentials Credentials) (*websocket.Conn, error) {
+	u, err := url.Parse(strings.TrimSuffix(c.config.Server, "/") + path)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+credentials.Key)
+	conn, _, err := websocket.DefaultDialer.DialContext(c.ctx, u.String(), header)
+	return conn, err
+}
@@ -0,0 +1,236 @@
+package agent
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/runner"
+	"github.com/gorilla/websocket"
+)
+
+var (
+	ErrNotConnected   = errors.New("agent is not connected")
+	ErrSessionTimeout = errors.New("agent did not open the session in time")
+	ErrUnknownSession = errors.New("unknown session")
+	ErrExpectedHello  = errors.New("expected hello message")
+)
+
+// Hub keeps control connections of agents that dialed the server and hands
+// out shell sessions on them.
+type Hub struct {
+	mu       sync.Mutex
+	agents   map[string]*agentConn
+	sessions map[string]pendingSession
+}
+
+type agentConn struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+type pendingSession struct {
+	agent string
+	conn  chan *websocket.Conn
+}
+
+var hub *Hub
+
+func Get() *Hub {
+	if hub == nil {
+		panic("agent hub is not initialized")
+	}
+	return hub
+}
+
+func Init() error {
+	if hub != nil {
+		panic("agent hub is already initialized")
+	}
+
+	hub = &Hub{
+		agents:   map[string]*agentConn{},
+		sessions: map[string]pendingSession{},
+	}
+
+	return nil
+}
+
+// Accept reads the hello message and registers the control connection of the
+// agent, replacing any previous one. The returned channel is closed once the
+// connection is lost.
+func (h *Hub) Accept(name string, conn *websocket.Conn) (Message, chan any, error) {
+	var hello Message
+	conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	if err := conn.ReadJSON(&hello); err != nil {
+		conn.Close()
+		return Message{}, nil, err
+	}
+	if hello.Type != MessageHello {
+		conn.Close()
+		return Message{}, nil, ErrExpectedHello
+	}
+
+	ac := &agentConn{conn: conn}
+	h.mu.Lock()
+	if prev, ok := h.agents[name]; ok {
+		prev.conn.Close()
+	}
+	h.agents[name] = ac
+	h.mu.Unlock()
+
+	done := make(chan any)
+	go h.keepalive(name, ac, done)
+	go func() {
+		defer close(done)
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongTimeout))
+		})
+		for {
+			conn.SetReadDeadline(time.Now().Add(pongTimeout))
+			if _, _, err := conn.NextReader(); err != nil {
+				h.remove(name, ac)
+				return
+			}
+		}
+	}()
+	return hello, done, nil
+}
+
+func (h *Hub) Connected(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.agents[name]
+	return ok
+}
+
+func (h *Hub) Disconnect(name string) {
+	h.mu.Lock()
+	ac, ok := h.agents[name]
+	h.mu.Unlock()
+	if ok {
+		h.remove(name, ac)
+	}
+}
+
+// NewRunner asks the agent to dial back a shell session and wraps it in the
+// runner used for every other worker type.
+func (h *Hub) NewRunner(name string) (*runner.Runner, error) {
+	h.mu.Lock()
+	ac, ok := h.agents[name]
+	h.mu.Unlock()
+	if !ok {
+		return &runner.Runner{}, ErrNotConnected
+	}
+
+	id, err := sessionID()
+	if err != nil {
+		return &runner.Runner{}, err
+	}
+	pending := pendingSession{agent: name, conn: make(chan *websocket.Conn, 1)}
+	h.mu.Lock()
+	h.sessions[id] = pending
+	h.mu.Unlock()
+
+	if err := ac.send(Message{Type: MessageSession, Session: id}); err != nil {
+		h.abandon(id, pending)
+		return &runner.Runner{}, err
+	}
+
+	select {
+	case conn := <-pending.conn:
+		s := newStream(conn)
+		_runner := runner.NewRunner(s, s)
+		_runner.OnShutdown = s.Close
+		return _runner, nil
+	case <-time.After(sessionTimeout):
+		h.abandon(id, pending)
+		return &runner.Runner{}, ErrSessionTimeout
+	}
+}
+
+// abandon stops waiting for the session, closing the connection if the agent
+// dialed it meanwhile.
+func (h *Hub) abandon(id string, pending pendingSession) {
+	h.mu.Lock()
+	_, waiting := h.sessions[id]
+	delete(h.sessions, id)
+	h.mu.Unlock()
+	if !waiting {
+		(<-pending.conn).Close()
+	}
+}
+
+// Session hands the connection dialed by the agent over to the waiting runner.
+// Each session is claimed once, any other connection is closed.
+func (h *Hub) Session(name, id string, conn *websocket.Conn) error {
+	h.mu.Lock()
+	pending, ok := h.sessions[id]
+	if ok && pending.agent == name {
+		delete(h.sessions, id)
+	}
+	h.mu.Unlock()
+	if !ok || pending.agent != name {
+		conn.Close()
+		return ErrUnknownSession
+	}
+	pending.conn <- conn
+	return nil
+}
+
+func (h *Hub) remove(name string, ac *agentConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.agents[name] == ac {
+		delete(h.agents, name)
+	}
+	ac.conn.Close()
+}
+
+func (h *Hub) keepalive(name string, ac *agentConn, done chan any) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ac.mu.Lock()
+			err := ac.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+			ac.mu.Unlock()
+			if err != nil {
+				h.remove(name, ac)
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+func (ac *agentConn) send(m Message) error {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return ac.conn.WriteJSON(m)
+}
+
+func sessionID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func Shutdown() error {
+	h := Get()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for name, ac := range h.agents {
+		ac.conn.Close()
+		delete(h.agents, name)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// connect returns server side of a new websocket connection together with
+// its client side.
+func connect(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+	accepted := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		accepted <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return <-accepted, client
+}
+
+// closed reports whether the server closed the connection of the client.
+func closed(client *websocket.Conn) bool {
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := client.ReadMessage()
+	return err != nil && !strings.Contains(err.Error(), "timeout")
+}
+
+func testHub(sessions map[string]pendingSession) *Hub {
+	return &Hub{agents: map[string]*agentConn{}, sessions: sessions}
+}
+
+func TestSessionClaimedOnce(t *testing.T) {
+	pending := pendingSession{agent: "a", conn: make(chan *websocket.Conn, 1)}
+	h := testHub(map[string]pendingSession{"s": pending})
+
+	first, _ := connect(t)
+	if err := h.Session("a", "s", first); err != nil {
+		t.Fatal(err)
+	}
+	second, client := connect(t)
+	if err := h.Session("a", "s", second); err != ErrUnknownSession {
+		t.Errorf("second connection of session error = %v, want %v", err, ErrUnknownSession)
+	}
+	if !closed(client) {
+		t.Error("second connection of session left open")
+	}
+	if conn := <-pending.conn; conn != first {
+		t.Error("waiter did not get first connection")
+	}
+}
+
+func TestSessionOfOtherAgentRejected(t *testing.T) {
+	h := testHub(map[string]pendingSession{"s": {agent: "a", conn: make(chan *websocket.Conn, 1)}})
+	conn, client := connect(t)
+	if err := h.Session("b", "s", conn); err != ErrUnknownSession {
+		t.Errorf("Session() error = %v, want %v", err, ErrUnknownSession)
+	}
+	if !closed(client) {
+		t.Error("connection of other agent left open")
+	}
+	if _, ok := h.sessions["s"]; !ok {
+		t.Error("session of the agent dropped")
+	}
+}
+
+func TestAbandonedSessionConnectionClosed(t *testing.T) {
+	t.Run("connection after timeout", func(t *testing.T) {
+		pending := pendingSession{agent: "a", conn: make(chan *websocket.Conn, 1)}
+		h := testHub(map[string]pendingSession{"s": pending})
+		h.abandon("s", pending)
+
+		conn, client := connect(t)
+		if err := h.Session("a", "s", conn); err != ErrUnknownSession {
+			t.Errorf("Session() error = %v, want %v", err, ErrUnknownSession)
+		}
+		if !closed(client) {
+			t.Error("late connection left open")
+		}
+	})
+	t.Run("connection racing timeout", func(t *testing.T) {
+		pending := pendingSession{agent: "a", conn: make(chan *websocket.Conn, 1)}
+		h := testHub(map[string]pendingSession{"s": pending})
+
+		conn, client := connect(t)
+		if err := h.Session("a", "s", conn); err != nil {
+			t.Fatal(err)
+		}
+		h.abandon("s", pending)
+		if !closed(client) {
+			t.Error("connection handed to timed out waiter left open")
+		}
+	})
+}
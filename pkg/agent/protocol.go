@@ -0,0 +1,27 @@
+package agent
+
+import "time"
+
+const (
+	// MessageHello is sent by the agent right after connecting.
+	MessageHello = "hello"
+	// MessageSession asks the agent to open a shell session under given ID.
+	MessageSession = "session"
+)
+
+const (
+	pingInterval   = 30 * time.Second
+	pongTimeout    = 2 * pingInterval
+	writeTimeout   = 10 * time.Second
+	sessionTimeout = 15 * time.Second
+)
+
+// Message is exchanged as JSON over the control connection, while shell
+// sessions use separate connections carrying raw binary frames.
+type Message struct {
+	Type     string            `json:"type"`
+	Session  string            `json:"session,omitempty"`
+	System   string            `json:"system,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Capacity int               `json:"capacity,omitempty"`
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"io"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// stream adapts the websocket connection to the byte stream expected by
+// runner.Runner and the agent's shell.
+type stream struct {
+	conn   *websocket.Conn
+	reader io.Reader
+	once   sync.Once
+}
+
+func newStream(conn *websocket.Conn) *stream {
+	return &stream{conn: conn}
+}
+
+func (s *stream) Read(p []byte) (int, error) {
+	for {
+		if s.reader == nil {
+			_, reader, err := s.conn.NextReader()
+			if err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					return 0, io.EOF
+				}
+				return 0, err
+			}
+			s.reader = reader
+		}
+		n, err := s.reader.Read(p)
+		if err == io.EOF {
+			s.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (s *stream) Write(p []byte) (int, error) {
+	if err := s.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *stream) Close() error {
+	var err error
+	s.once.Do(func() {
+		s.conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		err = s.conn.Close()
+	})
+	return err
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/agent"
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gg-mike/ccli/pkg/scheduler"
+	"gorm.io/gorm"
+)
+
+func GetAgentTokens() ([]model.AgentToken, error) {
+	tokens := []model.AgentToken{}
+	if err := db.Get().Order("id").Find(&tokens).Error; err != nil {
+		return []model.AgentToken{}, ErrDatabase
+	}
+	return tokens, nil
+}
+
+// ConnectAgent updates the worker with what the agent advertised on connect
+// and makes it available for binding.
+func ConnectAgent(name string, hello agent.Message) error {
+	err := db.Get().Transaction(func(tx *gorm.DB) error {
+		var worker model.Worker
+		if err := tx.First(&worker, &model.Worker{Name: name}).Error; err != nil {
+			return err
+		}
+		// Map updates bypass the json serializer of the column.
+		if hello.Labels == nil {
+			hello.Labels = model.Labels{}
+		}
+		labels, err := json.Marshal(hello.Labels)
+		if err != nil {
+			return err
+		}
+		status := model.WorkerIdle
+		if worker.ActiveBuilds > 0 {
+			status = model.WorkerUsed
+		}
+		return tx.Model(&worker).UpdateColumns(map[string]any{
+			"system":        hello.System,
+			"labels":        string(labels),
+			"capacity":      hello.Capacity,
+			"status":        status,
+			"status_reason": "",
+			"failures":      0,
+			"last_seen_at":  sql.NullTime{Time: time.Now(), Valid: true},
+		}).Error
+	})
+	if err != nil {
+		return ErrDatabase
+	}
+	go scheduler.Get().ChangeInWorkers()
+	return nil
+}
+
+func DisconnectAgent(name string) error {
+	if err := db.Get().Model(&model.Worker{}).Where("name = ? AND is_agent", name).UpdateColumns(map[string]any{
+		"status":        model.WorkerUnreachable,
+		"status_reason": agent.ErrNotConnected.Error(),
+	}).Error; err != nil {
+		return ErrDatabase
+	}
+	return nil
+}
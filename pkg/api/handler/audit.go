@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Keys whose values never reach the audit log, regardless of resource.
+var sensitiveKeys = []string{"value", "private_key", "password", "token", "hash"}
+
+var schemaCache = &sync.Map{}
+
+// recordAudit stores the change of a single record, where before or after is
+// nil for creation and deletion respectively.
+func recordAudit(tx *gorm.DB, actor, action string, before, after any) error {
+	subject := after
+	if subject == nil {
+		subject = before
+	}
+	resource, resourceID, err := identify(tx, subject)
+	if err != nil {
+		return err
+	}
+
+	diff, err := auditDiff(before, after)
+	if err != nil {
+		return err
+	}
+
+	return tx.Session(&gorm.Session{NewDB: true}).Create(&model.AuditEntry{
+		Actor:      actor,
+		Action:     action,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Diff:       diff,
+	}).Error
+}
+
+func RecordAudit(actor, action string, before, after any) error {
+	if err := recordAudit(db.Get(), actor, action, before, after); err != nil {
+		return ErrDatabase
+	}
+	return nil
+}
+
+// identify returns table name and the primary key (or unique index when the
+// model has no primary key) of the record joined with '/'.
+func identify(tx *gorm.DB, m any) (string, string, error) {
+	s, err := schema.Parse(m, schemaCache, tx.NamingStrategy)
+	if err != nil {
+		return "", "", err
+	}
+
+	fields := s.PrimaryFields
+	if len(fields) == 0 {
+		for _, field := range s.Fields {
+			if _, ok := field.TagSettings["UNIQUEINDEX"]; ok {
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(m))
+	parts := []string{}
+	for _, field := range fields {
+		value, zero := field.ValueOf(tx.Statement.Context, rv)
+		if zero {
+			continue
+		}
+		if valuer, ok := value.(driver.Valuer); ok {
+			if value, err = valuer.Value(); err != nil || value == nil {
+				continue
+			}
+		}
+		parts = append(parts, fmt.Sprint(value))
+	}
+	return s.Table, strings.Join(parts, "/"), nil
+}
+
+func auditDiff(before, after any) (map[string]model.AuditChange, error) {
+	b, err := flatten(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := flatten(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := map[string]model.AuditChange{}
+	for key, value := range a {
+		if prev, ok := b[key]; !ok || !reflect.DeepEqual(prev, value) {
+			diff[key] = model.AuditChange{Before: b[key], After: value}
+		}
+	}
+	for key, value := range b {
+		if _, ok := a[key]; !ok {
+			diff[key] = model.AuditChange{Before: value}
+		}
+	}
+
+	for key, change := range diff {
+		if slices.Contains(sensitiveKeys, key) {
+			if change.Before != nil {
+				change.Before = model.AuditRedacted
+			}
+			if change.After != nil {
+				change.After = model.AuditRedacted
+			}
+			diff[key] = change
+		}
+	}
+	return diff, nil
+}
+
+// flatten converts the record to its JSON fields, skipping timestamps and
+// preloaded associations which are audited on their own.
+func flatten(m any) (map[string]any, error) {
+	out := map[string]any{}
+	if m == nil {
+		return out, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	for key, value := range out {
+		if key == "created_at" || key == "updated_at" || isAssociation(value) {
+			delete(out, key)
+		}
+	}
+	return out, nil
+}
+
+func isAssociation(value any) bool {
+	list, ok := value.([]any)
+	if !ok || len(list) == 0 {
+		return false
+	}
+	_, ok = list[0].(map[string]any)
+	return ok
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/model"
+	"gorm.io/gorm"
+)
+
+func GetEffectiveVariables(projectName, pipelineName string) ([]model.EffectiveVariable, error) {
+	err := db.Get().First(&model.Pipeline{}, &model.Pipeline{Name: pipelineName, ProjectName: projectName}).Error
+	switch err {
+	case nil:
+	case gorm.ErrRecordNotFound:
+		return []model.EffectiveVariable{}, ErrRecordNotFound
+	default:
+		return []model.EffectiveVariable{}, ErrDatabase
+	}
+
+	variables, err := model.EffectiveVariables(db.Get(), projectName, pipelineName)
+	if err != nil {
+		return []model.EffectiveVariable{}, ErrDatabase
+	}
+	return variables, nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/model"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -14,10 +15,10 @@ type Handler[T, TShort, TInput any] struct {
 
 type IHandler[T, TShort, TInput any] interface {
 	GetMany(page, size int, order string, filters map[string]any) ([]TShort, error)
-	Create(parent T, m TInput) error
+	Create(actor string, parent T, m TInput) error
 	GetOne(selector T) (T, error)
-	Update(selector T, m TInput) error
-	Delete(selector T, force bool) error
+	Update(actor string, selector T, m TInput) error
+	Delete(actor string, selector T, force bool) error
 }
 
 func NewHandler[T, TShort, TInput any](merge func(T, TInput) T) IHandler[T, TShort, TInput] {
@@ -39,11 +40,17 @@ func (h Handler[T, TShort, TInput]) GetMany(page, size int, order string, filter
 	return *o, nil
 }
 
-func (h Handler[T, TShort, TInput]) Create(parent T, m TInput) error {
+func (h Handler[T, TShort, TInput]) Create(actor string, parent T, m TInput) error {
 	_m := h.merge(parent, m)
-	err := db.Get().InstanceSet("input", m).Create(&_m).Error
+	err := db.Get().Transaction(func(tx *gorm.DB) error {
+		if err := tx.InstanceSet("input", m).Create(&_m).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, model.AuditCreate, nil, _m)
+	})
 	switch err {
 	case nil:
+		afterCommit(&_m)
 		return nil
 	case gorm.ErrDuplicatedKey:
 		return ErrDuplicate
@@ -67,9 +74,20 @@ func (h Handler[T, TShort, TInput]) GetOne(query T) (T, error) {
 	}
 }
 
-func (h Handler[T, TShort, TInput]) Delete(query T, force bool) error {
+func (h Handler[T, TShort, TInput]) Delete(actor string, query T, force bool) error {
 	o := new([]T)
-	if err := db.Get().Clauses(clause.Returning{}).InstanceSet("force", force).Where(&query).Delete(&o).Error; err != nil {
+	err := db.Get().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Returning{}).InstanceSet("force", force).Where(&query).Delete(&o).Error; err != nil {
+			return err
+		}
+		for _, m := range *o {
+			if err := recordAudit(tx, actor, model.AuditDelete, m, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return ErrDatabase
 	} else if len(*o) == 0 {
 		return ErrRecordNotFound
@@ -78,15 +96,22 @@ func (h Handler[T, TShort, TInput]) Delete(query T, force bool) error {
 	}
 }
 
-func (h Handler[T, TShort, TInput]) Update(selector T, m TInput) error {
+func (h Handler[T, TShort, TInput]) Update(actor string, selector T, m TInput) error {
 	prev, err := h.GetOne(selector)
 	if err != nil {
 		return err
 	}
 
-	err = db.Get().Model(&selector).InstanceSet("input", m).InstanceSet("prev", prev).Where(&selector).Updates(h.merge(prev, m)).Error
+	next := h.merge(prev, m)
+	err = db.Get().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&selector).InstanceSet("input", m).InstanceSet("prev", prev).Where(&selector).Updates(next).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, model.AuditUpdate, prev, next)
+	})
 	switch err {
 	case nil:
+		afterCommit(&selector)
 		return nil
 	case gorm.ErrDuplicatedKey:
 		return ErrDuplicate
@@ -97,6 +122,13 @@ func (h Handler[T, TShort, TInput]) Update(selector T, m TInput) error {
 	}
 }
 
+// afterCommit lets the model act on its committed change.
+func afterCommit(m any) {
+	if c, ok := m.(model.Committer); ok {
+		c.AfterCommit()
+	}
+}
+
 func paginate(page, size int, order string) (int, int, string, error) {
 	if page >= 0 && size < 0 {
 		return -1, -1, "", errors.New("page size cannot be lower than 0 for nonnegative page number")
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/engine/common"
+	"github.com/gg-mike/ccli/pkg/model"
+	"gorm.io/gorm"
+)
+
+// Number of recent successful builds of the pipeline used for estimates.
+const historySize = 10
+
+// GetQueueStatus orders the queue the way the binder would and estimates when
+// each build starts, assuming every build takes as long as its pipeline did on
+// average recently. Builds held back by limits go last.
+func GetQueueStatus() ([]model.QueueStatus, error) {
+	statuses := []model.QueueStatus{}
+	err := db.Get().Transaction(func(tx *gorm.DB) error {
+		var queue []model.QueueElem
+		if err := tx.Order("created_at").Find(&queue).Error; err != nil {
+			return err
+		}
+
+		planner, err := common.LoadPlanner(tx, queue)
+		if err != nil {
+			return err
+		}
+		ordered := []model.QueueElem{}
+		planned := map[string]bool{}
+		for elem, ok := planner.Next(); ok; elem, ok = planner.Next() {
+			planner.Bound(elem)
+			ordered = append(ordered, elem)
+			planned[elem.ID] = true
+		}
+		for _, elem := range queue {
+			if !planned[elem.ID] {
+				ordered = append(ordered, elem)
+			}
+		}
+
+		durations := map[string]time.Duration{}
+		duration := func(projectName, pipelineName string) (time.Duration, error) {
+			key := projectName + "/" + pipelineName
+			if d, ok := durations[key]; ok {
+				return d, nil
+			}
+			d, err := averageDuration(tx, projectName, pipelineName)
+			durations[key] = d
+			return d, err
+		}
+
+		slots, err := workerSlots(tx, duration)
+		if err != nil {
+			return err
+		}
+
+		for idx, elem := range ordered {
+			build := elem.Context.Build
+			status := model.QueueStatus{
+				ID:            elem.ID,
+				Position:      idx + 1,
+				Priority:      build.Priority,
+				WaitReason:    elem.WaitReason,
+				LastAttemptAt: elem.LastAttemptAt,
+				CreatedAt:     elem.CreatedAt,
+			}
+			d, err := duration(build.ProjectName, build.PipelineName)
+			if err != nil {
+				return err
+			}
+			if d > 0 {
+				status.EstimatedDuration = d.String()
+			}
+			if len(slots) > 0 {
+				sort.Slice(slots, func(i, j int) bool { return slots[i].Before(slots[j]) })
+				status.EstimatedStart = sql.NullTime{Time: slots[0], Valid: true}
+				slots[0] = slots[0].Add(d)
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	if err != nil {
+		return []model.QueueStatus{}, ErrDatabase
+	}
+	return statuses, nil
+}
+
+// workerSlots returns the time at which each build slot of the workers becomes
+// free.
+func workerSlots(tx *gorm.DB, duration func(string, string) (time.Duration, error)) ([]time.Time, error) {
+	var workers []model.Worker
+	if err := tx.Select("capacity").Where("status <> ? AND NOT draining", model.WorkerUnreachable).Find(&workers).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	slots := []time.Time{}
+	for _, worker := range workers {
+		for i := 0; i < worker.Capacity; i++ {
+			slots = append(slots, now)
+		}
+	}
+	if len(slots) == 0 {
+		return slots, nil
+	}
+
+	var running []model.Build
+	if err := tx.Select("project_name", "pipeline_name", "created_at").Where(&model.Build{Status: model.BuildRunning}).Order("created_at").Find(&running).Error; err != nil {
+		return nil, err
+	}
+	for _, build := range running {
+		d, err := duration(build.ProjectName, build.PipelineName)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(slots, func(i, j int) bool { return slots[i].Before(slots[j]) })
+		if end := build.CreatedAt.Add(d); end.After(slots[0]) {
+			slots[0] = end
+		}
+	}
+	return slots, nil
+}
+
+// averageDuration sums the step durations of recent successful builds of the
+// pipeline, zero when there are none.
+func averageDuration(tx *gorm.DB, projectName, pipelineName string) (time.Duration, error) {
+	var builds []model.Build
+	err := tx.Preload("Steps").
+		Where(&model.Build{ProjectName: projectName, PipelineName: pipelineName, Status: model.BuildSuccessful}).
+		Order("number desc").Limit(historySize).Find(&builds).Error
+	if err != nil || len(builds) == 0 {
+		return 0, err
+	}
+
+	var total time.Duration
+	for _, build := range builds {
+		for _, step := range build.Steps {
+			if d, err := time.ParseDuration(step.Duration); err == nil {
+				total += d
+			}
+		}
+	}
+	return total / time.Duration(len(builds)), nil
+}
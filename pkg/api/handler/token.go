@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func GetTokens(userName string) ([]model.Token, error) {
+	tokens := []model.Token{}
+	if err := db.Get().Where(&model.Token{UserName: userName}).Find(&tokens).Error; err != nil {
+		return []model.Token{}, ErrDatabase
+	}
+	return tokens, nil
+}
+
+// DeleteToken revokes the token, restricted to the given owner unless empty.
+func DeleteToken(actor string, id uint, owner string) error {
+	tokens := []model.Token{}
+	err := db.Get().Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Returning{}).Where("id = ?", id)
+		if owner != "" {
+			query = query.Where("user_name = ?", owner)
+		}
+		if err := query.Delete(&tokens).Error; err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			if err := recordAudit(tx, actor, model.AuditDelete, token, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	switch {
+	case err != nil:
+		return ErrDatabase
+	case len(tokens) == 0:
+		return ErrRecordNotFound
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/executor"
+	"github.com/gg-mike/ccli/pkg/model"
+	"gorm.io/gorm"
+)
+
+func GetWorker(workerName string) (model.Worker, error) {
+	var m model.Worker
+	switch err := db.Get().First(&m, &model.Worker{Name: workerName}).Error; err {
+	case nil:
+		return m, nil
+	case gorm.ErrRecordNotFound:
+		return model.Worker{}, ErrRecordNotFound
+	default:
+		return model.Worker{}, ErrDatabase
+	}
+}
+
+func ConfirmHostKey(actor, workerName, fingerprint string) (model.Worker, error) {
+	return updateWorker(actor, workerName, func(tx *gorm.DB, m *model.Worker) error {
+		return executor.ConfirmHostKey(tx, m, fingerprint)
+	})
+}
+
+func RotateHostKey(actor, workerName, fingerprint string) (model.Worker, error) {
+	return updateWorker(actor, workerName, func(tx *gorm.DB, m *model.Worker) error {
+		return executor.RotateHostKey(tx, m, fingerprint)
+	})
+}
+
+func DrainWorker(actor, workerName string, deadline sql.NullTime) (model.Worker, error) {
+	return updateWorker(actor, workerName, func(tx *gorm.DB, m *model.Worker) error {
+		return m.Drain(tx, deadline)
+	})
+}
+
+func UndrainWorker(actor, workerName string) (model.Worker, error) {
+	return updateWorker(actor, workerName, func(tx *gorm.DB, m *model.Worker) error {
+		return m.Undrain(tx)
+	})
+}
+
+func updateWorker(actor, workerName string, update func(*gorm.DB, *model.Worker) error) (model.Worker, error) {
+	var m model.Worker
+	err := db.Get().Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&m, &model.Worker{Name: workerName}).Error; err != nil {
+			return err
+		}
+		prev := m
+		if err := update(tx, &m); err != nil {
+			return fmt.Errorf("%w: %v", ErrConflict, err)
+		}
+		return recordAudit(tx, actor, model.AuditUpdate, prev, m)
+	})
+	switch err {
+	case nil:
+		return m, nil
+	case gorm.ErrRecordNotFound:
+		return model.Worker{}, ErrRecordNotFound
+	}
+	if errors.Is(err, ErrConflict) {
+		return model.Worker{}, err
+	}
+	return model.Worker{}, ErrDatabase
+}
@@ -0,0 +1,190 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/agent"
+	"github.com/gg-mike/ccli/pkg/api/handler"
+	"github.com/gg-mike/ccli/pkg/auth"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+var upgrader = websocket.Upgrader{}
+
+// InitAgentRouter registers endpoints used by agents themselves, which do not
+// authenticate as users.
+func InitAgentRouter(base *gin.RouterGroup) {
+	_rg := base.Group("/agents")
+
+	_rg.POST("/register", registerAgent())
+	_rg.GET("/connect", auth.AuthenticateAgent(), connectAgent())
+	_rg.GET("/sessions/:session_id", auth.AuthenticateAgent(), agentSession())
+}
+
+func InitAgentTokenRouter(base *gin.RouterGroup) {
+	_rg := base.Group("/agents/tokens", auth.Require(model.RoleAdmin))
+
+	_rg.GET("", getManyAgentTokens())
+	_rg.POST("", createAgentToken())
+}
+
+// @Summary  Register agent
+// @ID       register-agent
+// @Tags     agents
+// @Accept   json
+// @Produce  json
+// @Param    registration body agent.Registration true "Agent registration with one-time token"
+// @Success  201 {object} agent.Credentials "Credentials for later connections, shown only once"
+// @Failure  400 {string} Error in request
+// @Failure  401 {string} Invalid token
+// @Failure  403 {string} Token scoped to another pool
+// @Failure  409 {string} Worker already exists
+// @Failure  500 {string} Database error
+// @Router   /agents/register [post]
+func registerAgent() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		m := agent.Registration{}
+		if err := ctx.BindJSON(&m); err != nil {
+			ctx.String(http.StatusBadRequest, "error in json: [%v]", err)
+			return
+		}
+		if m.Name == "" || m.Token == "" {
+			ctx.String(http.StatusBadRequest, "fields 'name' and 'token' are required")
+			return
+		}
+
+		credentials, worker, err := auth.RegisterAgent(m)
+		switch {
+		case err == nil:
+		case err == auth.ErrInvalidToken:
+			ctx.String(http.StatusUnauthorized, err.Error())
+			return
+		case err == auth.ErrPoolMismatch:
+			ctx.String(http.StatusForbidden, err.Error())
+			return
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			ctx.String(http.StatusConflict, "worker [%s] already exists", m.Name)
+			return
+		default:
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+			return
+		}
+		if err := handler.RecordAudit("agent:"+worker.Name, model.AuditCreate, nil, worker); err != nil {
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+			return
+		}
+		ctx.JSON(http.StatusCreated, credentials)
+	}
+}
+
+// @Summary  Open agent control connection
+// @ID       connect-agent
+// @Tags     agents
+// @Success  101
+// @Failure  401 {string} Invalid agent key
+// @Router   /agents/connect [get]
+func connectAgent() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		name := auth.GetAgent(ctx)
+		conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+		if err != nil {
+			return
+		}
+		hello, done, err := agent.Get().Accept(name, conn)
+		if err != nil {
+			return
+		}
+		if err := handler.ConnectAgent(name, hello); err != nil {
+			agent.Get().Disconnect(name)
+			return
+		}
+		go func() {
+			<-done
+			if !agent.Get().Connected(name) {
+				handler.DisconnectAgent(name)
+			}
+		}()
+	}
+}
+
+// @Summary  Open agent shell session
+// @ID       agent-session
+// @Tags     agents
+// @Param    session_id path string true "Session ID sent over control connection"
+// @Success  101
+// @Failure  401 {string} Invalid agent key
+// @Router   /agents/sessions/{session_id} [get]
+func agentSession() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+		if err != nil {
+			return
+		}
+		agent.Get().Session(auth.GetAgent(ctx), ctx.Param("session_id"), conn)
+	}
+}
+
+// @Summary  Get agent registration tokens
+// @ID       many-agent-tokens
+// @Tags     agents
+// @Produce  json
+// @Success  200 {object} []model.AgentToken "List of registration tokens"
+// @Failure  500 {string} Database error
+// @Router   /agents/tokens [get]
+func getManyAgentTokens() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tokens, err := handler.GetAgentTokens()
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+			return
+		}
+		ctx.JSON(http.StatusOK, tokens)
+	}
+}
+
+// @Summary  Create agent registration token
+// @ID       create-agent-token
+// @Tags     agents
+// @Accept   json
+// @Produce  json
+// @Param    token body model.AgentTokenInput true "New registration token"
+// @Success  201 {object} TokenOutput "Issued one-time token, shown only once"
+// @Failure  400 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /agents/tokens [post]
+func createAgentToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		m := model.AgentTokenInput{}
+		if ctx.Request.ContentLength != 0 {
+			if err := ctx.BindJSON(&m); err != nil {
+				ctx.String(http.StatusBadRequest, "error in json: [%v]", err)
+				return
+			}
+		}
+
+		var ttl time.Duration
+		if m.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(m.TTL); err != nil {
+				ctx.String(http.StatusBadRequest, "error parsing 'ttl': [%v]", err)
+				return
+			}
+		}
+
+		value, token, err := auth.NewAgentToken(actor(ctx), m.Pool, ttl)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+			return
+		}
+		if err := handler.RecordAudit(actor(ctx), model.AuditCreate, nil, token); err != nil {
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+			return
+		}
+		ctx.JSON(http.StatusCreated, TokenOutput{Token: value, ID: token.ID, ExpiresAt: token.ExpiresAt.Time})
+	}
+}
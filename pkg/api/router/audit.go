@@ -0,0 +1,99 @@
+package router
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditRouter = IRouter[model.AuditEntry, model.AuditEntry, struct{}]
+
+func InitAuditRouter(base *gin.RouterGroup) {
+	r := NewRouter[model.AuditEntry, model.AuditEntry, struct{}](
+		Access{Read: model.RoleAdmin, Write: model.RoleAdmin},
+		// FILTER
+		func(ctx *gin.Context) map[string]any {
+			filters := map[string]any{}
+			for key := range ctx.Request.URL.Query() {
+				switch key {
+				case "actor":
+					filters["actor = ?"] = ctx.Query(key)
+				case "action":
+					filters["action IN ?"] = ctx.QueryArray(key)
+				case "resource":
+					filters["resource IN ?"] = ctx.QueryArray(key)
+				case "resource_id":
+					filters["resource_id LIKE ?"] = "%" + ctx.Query(key) + "%"
+				case "since":
+					filters["created_at >= ?"] = ctx.Query(key)
+				case "until":
+					filters["created_at <= ?"] = ctx.Query(key)
+				}
+			}
+
+			return filters
+		},
+		// GET SELECTOR
+		func(params gin.Params) (model.AuditEntry, error) {
+			entryID, ok := params.Get("entry_id")
+			if !ok {
+				return model.AuditEntry{}, errors.New("missing param 'entry_id'")
+			}
+			_entryID, err := strconv.Atoi(entryID)
+			if err != nil {
+				return model.AuditEntry{}, errors.New("error parsing 'entry_id'")
+			}
+			return model.AuditEntry{ID: uint(_entryID)}, nil
+		},
+		// GET PARENT
+		func(params gin.Params) (model.AuditEntry, error) {
+			return model.AuditEntry{}, nil
+		},
+		// MERGE
+		func(left model.AuditEntry, right struct{}) model.AuditEntry {
+			return left
+		},
+	)
+
+	_rg := base.Group("/audit", r.Authorize())
+
+	_rg.GET("", getAudit(r))
+	_rg.GET(":entry_id", getAuditEntry(r))
+}
+
+// @Summary  Get audit log
+// @ID       audit
+// @Tags     audit
+// @Produce  json
+// @Param    page        query int      false "Page number"
+// @Param    size        query int      false "Page size"
+// @Param    order       query string   false "Order by field"
+// @Param    actor       query string   false "Actor (exact)"
+// @Param    action      query []string false "Action (possible values)"
+// @Param    resource    query []string false "Resource table (possible values)"
+// @Param    resource_id query string   false "Resource ID (pattern)"
+// @Param    since       query string   false "Earliest timestamp (RFC 3339)"
+// @Param    until       query string   false "Latest timestamp (RFC 3339)"
+// @Success  200 {object} []model.AuditEntry "List of audit entries"
+// @Failure  400 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /audit [get]
+func getAudit(r AuditRouter) gin.HandlerFunc {
+	return r.GetMany
+}
+
+// @Summary  Get the single audit entry
+// @ID       single-audit-entry
+// @Tags     audit
+// @Produce  json
+// @Param    entry_id path int true "Audit entry ID"
+// @Success  201 {object} model.AuditEntry "Requested audit entry"
+// @Failure  400 {string} Error in request
+// @Failure  404 {string} No record found
+// @Failure  500 {string} Database error
+// @Router   /audit/{entry_id} [get]
+func getAuditEntry(r AuditRouter) gin.HandlerFunc {
+	return r.GetOne
+}
@@ -0,0 +1,191 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/api/handler"
+	"github.com/gg-mike/ccli/pkg/auth"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gin-gonic/gin"
+)
+
+const stateCookie = "ccli_oidc_state"
+
+type TokenOutput struct {
+	Token     string    `json:"token"`
+	ID        uint      `json:"id"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+func InitAuthRouter(base *gin.RouterGroup) {
+	_rg := base.Group("/auth")
+
+	_rg.GET("/login", login())
+	_rg.GET("/callback", callback())
+}
+
+func InitTokenRouter(base *gin.RouterGroup) {
+	_rg := base.Group("/tokens")
+
+	_rg.GET("", getManyTokens())
+	_rg.POST("", createToken())
+	_rg.DELETE(":token_id", deleteToken())
+}
+
+// @Summary  Start OIDC login
+// @ID       login
+// @Tags     auth
+// @Success  302
+// @Failure  404 {string} OIDC not configured
+// @Router   /auth/login [get]
+func login() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		url, state, err := auth.LoginURL()
+		switch err {
+		case nil:
+			ctx.SetCookie(stateCookie, state, 600, "/", "", ctx.Request.TLS != nil, true)
+			ctx.Redirect(http.StatusFound, url)
+		case auth.ErrOIDCDisabled:
+			ctx.String(http.StatusNotFound, err.Error())
+		default:
+			ctx.String(http.StatusInternalServerError, err.Error())
+		}
+	}
+}
+
+// @Summary  Finish OIDC login
+// @ID       login-callback
+// @Tags     auth
+// @Produce  json
+// @Param    code  query string true "Authorization code"
+// @Param    state query string true "Login state"
+// @Success  200 {object} TokenOutput "Issued session token"
+// @Failure  401 {string} Login failed
+// @Router   /auth/callback [get]
+func callback() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		state, err := ctx.Cookie(stateCookie)
+		if err != nil || state == "" || state != ctx.Query("state") {
+			ctx.String(http.StatusUnauthorized, auth.ErrInvalidState.Error())
+			return
+		}
+		ctx.SetCookie(stateCookie, "", -1, "/", "", ctx.Request.TLS != nil, true)
+
+		value, token, err := auth.Callback(ctx.Request.Context(), ctx.Query("code"))
+		if err != nil {
+			ctx.String(http.StatusUnauthorized, "login failed [%v]", err)
+			return
+		}
+		ctx.JSON(http.StatusOK, TokenOutput{Token: value, ID: token.ID, ExpiresAt: token.ExpiresAt.Time})
+	}
+}
+
+// @Summary  Get tokens of the current user
+// @ID       many-tokens
+// @Tags     auth
+// @Produce  json
+// @Success  200 {object} []model.Token "List of tokens"
+// @Failure  500 {string} Database error
+// @Router   /tokens [get]
+func getManyTokens() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, _ := auth.GetPrincipal(ctx)
+		tokens, err := handler.GetTokens(principal.Name)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+			return
+		}
+		ctx.JSON(http.StatusOK, tokens)
+	}
+}
+
+// @Summary  Create API token
+// @ID       create-token
+// @Tags     auth
+// @Accept   json
+// @Produce  json
+// @Param    token body model.TokenInput true "New token (user_name only for admins)"
+// @Success  201 {object} TokenOutput "Issued token, shown only once"
+// @Failure  400 {string} Error in request
+// @Failure  403 {string} Not allowed
+// @Failure  500 {string} Database error
+// @Router   /tokens [post]
+func createToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, _ := auth.GetPrincipal(ctx)
+		m := model.TokenInput{}
+		if err := ctx.BindJSON(&m); err != nil {
+			ctx.String(http.StatusBadRequest, "error in json: [%v]", err)
+			return
+		}
+
+		var ttl time.Duration
+		if m.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(m.TTL); err != nil {
+				ctx.String(http.StatusBadRequest, "error parsing 'ttl': [%v]", err)
+				return
+			}
+		}
+
+		if m.UserName == "" {
+			m.UserName = principal.Name
+		}
+		if m.UserName != principal.Name {
+			allowed, err := auth.Allowed(principal, "", model.RoleAdmin)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "error during database operations")
+				return
+			}
+			if !allowed {
+				ctx.String(http.StatusForbidden, "only admins can create tokens for other users")
+				return
+			}
+		}
+
+		value, token, err := auth.NewToken(m.Name, m.UserName, ttl)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+			return
+		}
+		if err := handler.RecordAudit(principal.Name, model.AuditCreate, nil, token); err != nil {
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+			return
+		}
+		ctx.JSON(http.StatusCreated, TokenOutput{Token: value, ID: token.ID, ExpiresAt: token.ExpiresAt.Time})
+	}
+}
+
+// @Summary  Revoke API token
+// @ID       delete-token
+// @Tags     auth
+// @Param    token_id path int true "Token ID"
+// @Success  200 {string} Success message
+// @Failure  404 {string} No record found
+// @Failure  500 {string} Database error
+// @Router   /tokens/{token_id} [delete]
+func deleteToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, _ := auth.GetPrincipal(ctx)
+		tokenID, err := strconv.Atoi(ctx.Param("token_id"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, "error parsing 'token_id'")
+			return
+		}
+
+		owner := principal.Name
+		if allowed, _ := auth.Allowed(principal, "", model.RoleAdmin); allowed {
+			owner = ""
+		}
+		switch handler.DeleteToken(principal.Name, uint(tokenID), owner) {
+		case nil:
+			ctx.String(http.StatusOK, "deleted record")
+		case handler.ErrRecordNotFound:
+			ctx.String(http.StatusNotFound, "record not found")
+		case handler.ErrDatabase:
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+		}
+	}
+}
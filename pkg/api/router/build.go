@@ -8,10 +8,11 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-type BuildRouter = IRouter[model.Build, model.BuildShort, struct{}]
+type BuildRouter = IRouter[model.Build, model.BuildShort, model.BuildInput]
 
 func InitBuildRouter(pipeline *gin.RouterGroup) {
-	r := NewRouter[model.Build, model.BuildShort, struct{}](
+	r := NewRouter[model.Build, model.BuildShort, model.BuildInput](
+		Access{Read: model.RoleViewer, Write: model.RoleDeveloper},
 		// FILTER
 		func(ctx *gin.Context) map[string]any {
 			filters := map[string]any{}
@@ -59,12 +60,15 @@ func InitBuildRouter(pipeline *gin.RouterGroup) {
 			return model.Build{PipelineName: pipelineName, ProjectName: projectName}, nil
 		},
 		// MERGE
-		func(left model.Build, right struct{}) model.Build {
+		func(left model.Build, right model.BuildInput) model.Build {
+			if right.Priority != nil {
+				left.Priority = *right.Priority
+			}
 			return left
 		},
 	)
 
-	_rg := pipeline.Group(":pipeline_name/builds")
+	_rg := pipeline.Group(":pipeline_name/builds", r.Authorize())
 
 	_rg.GET("", getManyBuilds(r))
 	_rg.POST("", createBuild(r))
@@ -95,8 +99,9 @@ func getManyBuilds(r BuildRouter) gin.HandlerFunc {
 // @ID       create-build
 // @Tags     builds
 // @Accept   json
-// @Param    project_name  path string true "Project name"
-// @Param    pipeline_name path string true "Pipeline name"
+// @Param    project_name  path string           true  "Project name"
+// @Param    pipeline_name path string           true  "Pipeline name"
+// @Param    build         body model.BuildInput false "Build options"
 // @Success  202 {string} Success message
 // @Failure  400 {string} Error in request
 // @Failure  500 {string} Database error
@@ -2,7 +2,9 @@ package router
 
 import (
 	"errors"
+	"net/http"
 
+	"github.com/gg-mike/ccli/pkg/api/handler"
 	"github.com/gg-mike/ccli/pkg/model"
 	"github.com/gin-gonic/gin"
 )
@@ -11,6 +13,7 @@ type PipelineRouter = IRouter[model.Pipeline, model.PipelineShort, model.Pipelin
 
 func InitPipelineRouter(project *gin.RouterGroup) *gin.RouterGroup {
 	r := NewRouter[model.Pipeline, model.PipelineShort, model.PipelineInput](
+		Access{Read: model.RoleViewer, Write: model.RoleDeveloper},
 		// FILTER
 		func(ctx *gin.Context) map[string]any {
 			filters := map[string]any{}
@@ -55,12 +58,14 @@ func InitPipelineRouter(project *gin.RouterGroup) *gin.RouterGroup {
 	)
 
 	_rg := project.Group(":project_name/pipelines")
+	guarded := _rg.Group("", r.Authorize())
 
-	_rg.GET("", getManyPipelines(r))
-	_rg.POST("", createPipeline(r))
-	_rg.GET(":pipeline_name", getOnePipeline(r))
-	_rg.PUT(":pipeline_name", updatePipeline(r))
-	_rg.DELETE(":pipeline_name", deletePipeline(r))
+	guarded.GET("", getManyPipelines(r))
+	guarded.POST("", createPipeline(r))
+	guarded.GET(":pipeline_name", getOnePipeline(r))
+	guarded.PUT(":pipeline_name", updatePipeline(r))
+	guarded.DELETE(":pipeline_name", deletePipeline(r))
+	guarded.GET(":pipeline_name/effective-variables", getEffectiveVariables())
 
 	return _rg
 }
@@ -141,3 +146,27 @@ func updatePipeline(r PipelineRouter) gin.HandlerFunc {
 func deletePipeline(r PipelineRouter) gin.HandlerFunc {
 	return r.Delete
 }
+
+// @Summary  Get effective variables
+// @ID       effective-variables
+// @Tags     pipelines
+// @Produce  json
+// @Param    project_name  path string true "Project name"
+// @Param    pipeline_name path string true "Pipeline name"
+// @Success  200 {object} []model.EffectiveVariable "Variables resolved for the pipeline"
+// @Failure  404 {string} No record found
+// @Failure  500 {string} Database error
+// @Router   /projects/{project_name}/pipelines/{pipeline_name}/effective-variables [get]
+func getEffectiveVariables() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		variables, err := handler.GetEffectiveVariables(ctx.Param("project_name"), ctx.Param("pipeline_name"))
+		switch err {
+		case nil:
+			ctx.JSON(http.StatusOK, variables)
+		case handler.ErrRecordNotFound:
+			ctx.String(http.StatusNotFound, "record not found")
+		case handler.ErrDatabase:
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package router
+
+import (
+	"errors"
+
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gin-gonic/gin"
+)
+
+type PoolRouter = IRouter[model.WorkerPool, model.WorkerPool, model.WorkerPoolInput]
+
+func InitPoolRouter(base *gin.RouterGroup) {
+	r := NewRouter[model.WorkerPool, model.WorkerPool, model.WorkerPoolInput](
+		Access{Read: model.RoleViewer, Write: model.RoleAdmin},
+		// FILTER
+		func(ctx *gin.Context) map[string]any {
+			filters := map[string]any{}
+			for key := range ctx.Request.URL.Query() {
+				switch key {
+				case "name":
+					filters["name LIKE ?"] = "%" + ctx.Query(key) + "%"
+				case "strategy":
+					filters["strategy = ?"] = ctx.Query(key)
+				}
+			}
+
+			return filters
+		},
+		// GET SELECTOR
+		func(params gin.Params) (model.WorkerPool, error) {
+			poolName, ok := params.Get("pool_name")
+			if !ok {
+				return model.WorkerPool{}, errors.New("missing param 'pool_name'")
+			}
+			return model.WorkerPool{Name: poolName}, nil
+		},
+		// GET PARENT
+		func(params gin.Params) (model.WorkerPool, error) {
+			return model.WorkerPool{}, nil
+		},
+		// MERGE
+		func(left model.WorkerPool, right model.WorkerPoolInput) model.WorkerPool {
+			left.Name = right.Name
+			left.Strategy = right.Strategy
+			return left
+		},
+	)
+
+	_rg := base.Group("/pools", r.Authorize())
+
+	_rg.GET("", getManyPools(r))
+	_rg.POST("", createPool(r))
+	_rg.GET(":pool_name", getOnePool(r))
+	_rg.PUT(":pool_name", updatePool(r))
+	_rg.DELETE(":pool_name", deletePool(r))
+}
+
+// @Summary  Get pools
+// @ID       many-pools
+// @Tags     pools
+// @Produce  json
+// @Param    page     query int    false "Page number"
+// @Param    size     query int    false "Page size"
+// @Param    order    query string false "Order by field"
+// @Param    name     query string false "Pool name (pattern)"
+// @Param    strategy query string false "Selection strategy"
+// @Success  200 {object} []model.WorkerPool "List of pools"
+// @Failure  400 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /pools [get]
+func getManyPools(r PoolRouter) gin.HandlerFunc {
+	return r.GetMany
+}
+
+// @Summary  Create new pool
+// @ID       create-pool
+// @Tags     pools
+// @Accept   json
+// @Param    pool body model.WorkerPoolInput true "New pool entry"
+// @Success  202 {string} Success message
+// @Failure  400 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /pools [post]
+func createPool(r PoolRouter) gin.HandlerFunc {
+	return r.Create
+}
+
+// @Summary  Get the single pool
+// @ID       single-pool
+// @Tags     pools
+// @Produce  json
+// @Param    pool_name path string true "Pool name"
+// @Success  201 {object} model.WorkerPool "Requested pool"
+// @Failure  400 {string} Error in request
+// @Failure  404 {string} No record found
+// @Failure  500 {string} Database error
+// @Router   /pools/{pool_name} [get]
+func getOnePool(r PoolRouter) gin.HandlerFunc {
+	return r.GetOne
+}
+
+// @Summary  Update pool
+// @ID       update-pool
+// @Tags     pools
+// @Accept   json
+// @Param    pool_name path string          true "Pool name"
+// @Param    pool      body model.WorkerPoolInput true "Updated pool entry"
+// @Success  200 {object} model.WorkerPool "Updated pool"
+// @Failure  400 {string} Error in request
+// @Failure  404 {string} No record found
+// @Failure  500 {string} Database error
+// @Router   /pools/{pool_name} [put]
+func updatePool(r PoolRouter) gin.HandlerFunc {
+	return r.Update
+}
+
+// @Summary  Delete pool
+// @ID       delete-pool
+// @Tags     pools
+// @Param    pool_name path string true "Pool name"
+// @Success  200 {string} Success message
+// @Failure  404 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /pools/{pool_name} [delete]
+func deletePool(r PoolRouter) gin.HandlerFunc {
+	return r.Delete
+}
@@ -11,6 +11,7 @@ type ProjectRouter = IRouter[model.Project, model.ProjectShort, model.ProjectInp
 
 func InitProjectRouter(base *gin.RouterGroup) *gin.RouterGroup {
 	r := NewRouter[model.Project, model.ProjectShort, model.ProjectInput](
+		Access{Read: model.RoleViewer, Write: model.RoleAdmin},
 		// FILTER
 		func(ctx *gin.Context) map[string]any {
 			filters := map[string]any{}
@@ -41,17 +42,20 @@ func InitProjectRouter(base *gin.RouterGroup) *gin.RouterGroup {
 		func(left model.Project, right model.ProjectInput) model.Project {
 			left.Name = right.Name
 			left.Repo = right.Repo
+			left.MaxConcurrent = right.MaxConcurrent
+			left.Weight = right.Weight
 			return left
 		},
 	)
 
 	_rg := base.Group("/projects")
+	guarded := _rg.Group("", r.Authorize())
 
-	_rg.GET("", getManyProjects(r))
-	_rg.POST("", createProject(r))
-	_rg.GET(":project_name", getOneProject(r))
-	_rg.PUT(":project_name", updateProject(r))
-	_rg.DELETE(":project_name", deleteProject(r))
+	guarded.GET("", getManyProjects(r))
+	guarded.POST("", createProject(r))
+	guarded.GET(":project_name", getOneProject(r))
+	guarded.PUT(":project_name", updateProject(r))
+	guarded.DELETE(":project_name", deleteProject(r))
 
 	return _rg
 }
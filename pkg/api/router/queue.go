@@ -3,7 +3,9 @@ package router
 import (
 	"errors"
 	"fmt"
+	"net/http"
 
+	"github.com/gg-mike/ccli/pkg/api/handler"
 	"github.com/gg-mike/ccli/pkg/model"
 	"github.com/gin-gonic/gin"
 )
@@ -12,6 +14,7 @@ type QueueRouter = IRouter[model.QueueElem, model.QueueElemShort, struct{}]
 
 func InitQueueRouter(base *gin.RouterGroup) {
 	r := NewRouter[model.QueueElem, model.QueueElemShort, struct{}](
+		Access{Read: model.RoleViewer, Write: model.RoleDeveloper},
 		// FILTER
 		func(ctx *gin.Context) map[string]any {
 			return map[string]any{}
@@ -42,9 +45,10 @@ func InitQueueRouter(base *gin.RouterGroup) {
 		},
 	)
 
-	_rg := base.Group("/queue")
+	_rg := base.Group("/queue", r.Authorize())
 
 	_rg.GET("", getQueue(r))
+	_rg.GET("status", getQueueStatus())
 	_rg.GET(":project_name/:pipeline_name/:build_number", getQueueElem(r))
 	_rg.DELETE(":project_name/:pipeline_name/:build_number", deleteQueueElem(r))
 }
@@ -64,6 +68,25 @@ func getQueue(r QueueRouter) gin.HandlerFunc {
 	return r.GetMany
 }
 
+// @Summary  Get queue status
+// @ID       queue-status
+// @Tags     queue
+// @Produce  json
+// @Success  200 {object} []model.QueueStatus "Queue in binding order with wait reasons and estimates"
+// @Failure  500 {string} Database error
+// @Router   /queue/status [get]
+func getQueueStatus() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		statuses, err := handler.GetQueueStatus()
+		switch err {
+		case nil:
+			ctx.JSON(http.StatusOK, statuses)
+		case handler.ErrDatabase:
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+		}
+	}
+}
+
 // @Summary  Get the single queue elem
 // @ID       single-queue-elem
 // @Tags     queue
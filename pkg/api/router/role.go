@@ -0,0 +1,129 @@
+package router
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gin-gonic/gin"
+)
+
+type RoleBindingRouter = IRouter[model.RoleBinding, model.RoleBinding, model.RoleBindingInput]
+
+func InitRoleBindingRouter(base, project *gin.RouterGroup) {
+	r := NewRouter[model.RoleBinding, model.RoleBinding, model.RoleBindingInput](
+		Access{Read: model.RoleViewer, Write: model.RoleAdmin},
+		// FILTER
+		func(ctx *gin.Context) map[string]any {
+			filters := map[string]any{}
+			if projectName, ok := ctx.Params.Get("project_name"); ok {
+				filters["project_name = ?"] = projectName
+			}
+			for key := range ctx.Request.URL.Query() {
+				switch key {
+				case "user_name":
+					filters["user_name = ?"] = ctx.Query(key)
+				case "team_name":
+					filters["team_name = ?"] = ctx.Query(key)
+				case "role":
+					filters["role IN ?"] = ctx.QueryArray(key)
+				}
+			}
+
+			return filters
+		},
+		// GET SELECTOR
+		func(params gin.Params) (model.RoleBinding, error) {
+			projectName, okProject := params.Get("project_name")
+			bindingID, ok := params.Get("binding_id")
+			if !ok {
+				return model.RoleBinding{}, errors.New("missing param 'binding_id'")
+			}
+			_bindingID, err := strconv.Atoi(bindingID)
+			if err != nil {
+				return model.RoleBinding{}, errors.New("error parsing 'binding_id'")
+			}
+			return model.RoleBinding{
+				ID:          uint(_bindingID),
+				ProjectName: sql.NullString{String: projectName, Valid: okProject},
+			}, nil
+		},
+		// GET PARENT
+		func(params gin.Params) (model.RoleBinding, error) {
+			projectName, okProject := params.Get("project_name")
+			return model.RoleBinding{ProjectName: sql.NullString{String: projectName, Valid: okProject}}, nil
+		},
+		// MERGE
+		func(left model.RoleBinding, right model.RoleBindingInput) model.RoleBinding {
+			left.UserName = sql.NullString{String: right.UserName, Valid: right.UserName != ""}
+			left.TeamName = sql.NullString{String: right.TeamName, Valid: right.TeamName != ""}
+			if !left.ProjectName.Valid {
+				left.ProjectName = sql.NullString{String: right.ProjectName, Valid: right.ProjectName != ""}
+			}
+			left.Role = right.Role
+			return left
+		},
+	)
+
+	{
+		_rg := base.Group("/roles", r.Authorize())
+
+		_rg.GET("", getManyRoleBindings(r))
+		_rg.POST("", createRoleBinding(r))
+		_rg.DELETE(":binding_id", deleteRoleBinding(r))
+	}
+	{
+		_rg := project.Group(":project_name/roles", r.Authorize())
+
+		_rg.GET("", getManyRoleBindings(r))
+		_rg.POST("", createRoleBinding(r))
+		_rg.DELETE(":binding_id", deleteRoleBinding(r))
+	}
+}
+
+// @Summary  Get role bindings
+// @Tags     roles
+// @Produce  json
+// @Param    project_name path  string   true  "Project name"
+// @Param    page         query int      false "Page number"
+// @Param    size         query int      false "Page size"
+// @Param    order        query string   false "Order by field"
+// @Param    user_name    query string   false "Bound user (exact)"
+// @Param    team_name    query string   false "Bound team (exact)"
+// @Param    role         query []string false "Role (possible values)"
+// @Success  200 {object} []model.RoleBinding "List of role bindings"
+// @Failure  400 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /roles [get]
+// @Router   /projects/{project_name}/roles [get]
+func getManyRoleBindings(r RoleBindingRouter) gin.HandlerFunc {
+	return r.GetMany
+}
+
+// @Summary  Create new role binding
+// @Tags     roles
+// @Accept   json
+// @Param    project_name path string                 true "Project name"
+// @Param    binding      body model.RoleBindingInput true "New role binding"
+// @Success  202 {string} Success message
+// @Failure  400 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /roles [post]
+// @Router   /projects/{project_name}/roles [post]
+func createRoleBinding(r RoleBindingRouter) gin.HandlerFunc {
+	return r.Create
+}
+
+// @Summary  Delete role binding
+// @Tags     roles
+// @Param    project_name path string true "Project name"
+// @Param    binding_id   path int    true "Role binding ID"
+// @Success  200 {string} Success message
+// @Failure  404 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /roles/{binding_id} [delete]
+// @Router   /projects/{project_name}/roles/{binding_id} [delete]
+func deleteRoleBinding(r RoleBindingRouter) gin.HandlerFunc {
+	return r.Delete
+}
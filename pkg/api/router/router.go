@@ -6,10 +6,18 @@ import (
 	"strconv"
 
 	"github.com/gg-mike/ccli/pkg/api/handler"
+	"github.com/gg-mike/ccli/pkg/auth"
 	"github.com/gin-gonic/gin"
 )
 
+// Access holds the minimal roles required to read and to modify a resource.
+type Access struct {
+	Read  string
+	Write string
+}
+
 type Router[T, TShort, TInput any] struct {
+	access      Access
 	filter      func(ctx *gin.Context) map[string]any
 	getSelector func(params gin.Params) (T, error)
 	getParent   func(params gin.Params) (T, error)
@@ -23,15 +31,19 @@ type IRouter[T, TShort, TInput any] interface {
 	GetOne(ctx *gin.Context)
 	Update(ctx *gin.Context)
 	Delete(ctx *gin.Context)
+
+	Authorize() gin.HandlerFunc
 }
 
 func NewRouter[T, TShort, TInput any](
+	access Access,
 	filter func(ctx *gin.Context) map[string]any,
 	getSelector func(params gin.Params) (T, error),
 	getParent func(params gin.Params) (T, error),
 	merge func(T, TInput) T,
 ) IRouter[T, TShort, TInput] {
 	return Router[T, TShort, TInput]{
+		access:      access,
 		filter:      filter,
 		getSelector: getSelector,
 		getParent:   getParent,
@@ -65,7 +77,7 @@ func (r Router[T, TShort, TInput]) Create(ctx *gin.Context) {
 	if err := ctx.BindJSON(&m); err != nil {
 		ctx.String(http.StatusBadRequest, "error in json: [%v]", err)
 	}
-	switch r.handler.Create(parent, m) {
+	switch r.handler.Create(actor(ctx), parent, m) {
 	case nil:
 		ctx.String(http.StatusAccepted, "added new record")
 	case handler.ErrDuplicate:
@@ -104,7 +116,7 @@ func (r Router[T, TShort, TInput]) Update(ctx *gin.Context) {
 	if err := ctx.BindJSON(&m); err != nil {
 		ctx.String(http.StatusBadRequest, "error in json: [%v]", err)
 	}
-	switch r.handler.Update(selector, m) {
+	switch r.handler.Update(actor(ctx), selector, m) {
 	case nil:
 		ctx.String(http.StatusAccepted, "updated record")
 	case handler.ErrRecordNotFound:
@@ -125,7 +137,7 @@ func (r Router[T, TShort, TInput]) Delete(ctx *gin.Context) {
 		return
 	}
 	_, force := ctx.GetQuery("force")
-	switch r.handler.Delete(selector, force) {
+	switch r.handler.Delete(actor(ctx), selector, force) {
 	case nil:
 		ctx.String(http.StatusOK, "deleted record")
 	case handler.ErrRecordNotFound:
@@ -135,6 +147,22 @@ func (r Router[T, TShort, TInput]) Delete(ctx *gin.Context) {
 	}
 }
 
+func (r Router[T, TShort, TInput]) Authorize() gin.HandlerFunc {
+	read, write := auth.Require(r.access.Read), auth.Require(r.access.Write)
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method == http.MethodGet {
+			read(ctx)
+		} else {
+			write(ctx)
+		}
+	}
+}
+
+func actor(ctx *gin.Context) string {
+	principal, _ := auth.GetPrincipal(ctx)
+	return principal.Name
+}
+
 func extractPagination(ctx *gin.Context) (int, int, string, error) {
 	var page, size int
 	var err error
@@ -12,6 +12,7 @@ type SecretRouter = IRouter[model.Secret, model.Secret, model.SecretInput]
 
 func InitSecretRouter(base, project, pipeline *gin.RouterGroup) {
 	r := NewRouter[model.Secret, model.Secret, model.SecretInput](
+		Access{Read: model.RoleViewer, Write: model.RoleAdmin},
 		// FILTER
 		func(ctx *gin.Context) map[string]any {
 			filters := map[string]any{}
@@ -55,7 +56,7 @@ func InitSecretRouter(base, project, pipeline *gin.RouterGroup) {
 	)
 
 	{
-		_rg := base.Group("/secrets")
+		_rg := base.Group("/secrets", r.Authorize())
 
 		_rg.GET("", getManySecrets(r))
 		_rg.POST("", createSecret(r))
@@ -63,7 +64,7 @@ func InitSecretRouter(base, project, pipeline *gin.RouterGroup) {
 		_rg.DELETE(":secret_key", deleteSecret(r))
 	}
 	{
-		_rg := project.Group(":project_name/secrets")
+		_rg := project.Group(":project_name/secrets", r.Authorize())
 
 		_rg.GET("", getManySecrets(r))
 		_rg.POST("", createSecret(r))
@@ -71,7 +72,7 @@ func InitSecretRouter(base, project, pipeline *gin.RouterGroup) {
 		_rg.DELETE(":secret_key", deleteSecret(r))
 	}
 	{
-		_rg := pipeline.Group(":pipeline_name/secrets")
+		_rg := pipeline.Group(":pipeline_name/secrets", r.Authorize())
 
 		_rg.GET("", getManySecrets(r))
 		_rg.POST("", createSecret(r))
@@ -0,0 +1,124 @@
+package router
+
+import (
+	"errors"
+
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gin-gonic/gin"
+)
+
+type TeamRouter = IRouter[model.Team, model.TeamShort, model.TeamInput]
+
+func InitTeamRouter(base *gin.RouterGroup) {
+	r := NewRouter[model.Team, model.TeamShort, model.TeamInput](
+		Access{Read: model.RoleViewer, Write: model.RoleAdmin},
+		// FILTER
+		func(ctx *gin.Context) map[string]any {
+			filters := map[string]any{}
+			for key := range ctx.Request.URL.Query() {
+				switch key {
+				case "name":
+					filters["name LIKE ?"] = "%" + ctx.Query(key) + "%"
+				}
+			}
+
+			return filters
+		},
+		// GET SELECTOR
+		func(params gin.Params) (model.Team, error) {
+			teamName, ok := params.Get("team_name")
+			if !ok {
+				return model.Team{}, errors.New("missing param 'team_name'")
+			}
+			return model.Team{Name: teamName}, nil
+		},
+		// GET PARENT
+		func(params gin.Params) (model.Team, error) {
+			return model.Team{}, nil
+		},
+		// MERGE
+		func(left model.Team, right model.TeamInput) model.Team {
+			left.Name = right.Name
+			left.Users = model.TeamMembers(right.Members)
+			return left
+		},
+	)
+
+	_rg := base.Group("/teams", r.Authorize())
+
+	_rg.GET("", getManyTeams(r))
+	_rg.POST("", createTeam(r))
+	_rg.GET(":team_name", getOneTeam(r))
+	_rg.PUT(":team_name", updateTeam(r))
+	_rg.DELETE(":team_name", deleteTeam(r))
+}
+
+// @Summary  Get teams
+// @ID       many-teams
+// @Tags     teams
+// @Produce  json
+// @Param    page  query int    false "Page number"
+// @Param    size  query int    false "Page size"
+// @Param    order query string false "Order by field"
+// @Param    name  query string false "Team name (pattern)"
+// @Success  200 {object} []model.TeamShort "List of teams"
+// @Failure  400 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /teams [get]
+func getManyTeams(r TeamRouter) gin.HandlerFunc {
+	return r.GetMany
+}
+
+// @Summary  Create new team
+// @ID       create-team
+// @Tags     teams
+// @Accept   json
+// @Param    team body model.TeamInput true "New team entry"
+// @Success  202 {string} Success message
+// @Failure  400 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /teams [post]
+func createTeam(r TeamRouter) gin.HandlerFunc {
+	return r.Create
+}
+
+// @Summary  Get the single team
+// @ID       single-team
+// @Tags     teams
+// @Produce  json
+// @Param    team_name path string true "Team name"
+// @Success  201 {object} model.Team "Requested team"
+// @Failure  400 {string} Error in request
+// @Failure  404 {string} No record found
+// @Failure  500 {string} Database error
+// @Router   /teams/{team_name} [get]
+func getOneTeam(r TeamRouter) gin.HandlerFunc {
+	return r.GetOne
+}
+
+// @Summary  Update team
+// @ID       update-team
+// @Tags     teams
+// @Accept   json
+// @Param    team_name path string          true "Team name"
+// @Param    team      body model.TeamInput true "Updated team entry"
+// @Success  200 {object} model.Team "Updated team"
+// @Failure  400 {string} Error in request
+// @Failure  404 {string} No record found
+// @Failure  500 {string} Database error
+// @Router   /teams/{team_name} [put]
+func updateTeam(r TeamRouter) gin.HandlerFunc {
+	return r.Update
+}
+
+// @Summary  Delete team
+// @ID       delete-team
+// @Tags     teams
+// @Param    team_name path string true "Team name"
+// @Success  200 {string} Success message
+// @Failure  404 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /teams/{team_name} [delete]
+func deleteTeam(r TeamRouter) gin.HandlerFunc {
+	return r.Delete
+}
@@ -0,0 +1,127 @@
+package router
+
+import (
+	"errors"
+
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gin-gonic/gin"
+)
+
+type UserRouter = IRouter[model.User, model.UserShort, model.UserInput]
+
+func InitUserRouter(base *gin.RouterGroup) {
+	r := NewRouter[model.User, model.UserShort, model.UserInput](
+		Access{Read: model.RoleViewer, Write: model.RoleAdmin},
+		// FILTER
+		func(ctx *gin.Context) map[string]any {
+			filters := map[string]any{}
+			for key := range ctx.Request.URL.Query() {
+				switch key {
+				case "name":
+					filters["name LIKE ?"] = "%" + ctx.Query(key) + "%"
+				case "email":
+					filters["email LIKE ?"] = "%" + ctx.Query(key) + "%"
+				}
+			}
+
+			return filters
+		},
+		// GET SELECTOR
+		func(params gin.Params) (model.User, error) {
+			userName, ok := params.Get("user_name")
+			if !ok {
+				return model.User{}, errors.New("missing param 'user_name'")
+			}
+			return model.User{Name: userName}, nil
+		},
+		// GET PARENT
+		func(params gin.Params) (model.User, error) {
+			return model.User{}, nil
+		},
+		// MERGE
+		func(left model.User, right model.UserInput) model.User {
+			left.Name = right.Name
+			left.Email = right.Email
+			return left
+		},
+	)
+
+	_rg := base.Group("/users", r.Authorize())
+
+	_rg.GET("", getManyUsers(r))
+	_rg.POST("", createUser(r))
+	_rg.GET(":user_name", getOneUser(r))
+	_rg.PUT(":user_name", updateUser(r))
+	_rg.DELETE(":user_name", deleteUser(r))
+}
+
+// @Summary  Get users
+// @ID       many-users
+// @Tags     users
+// @Produce  json
+// @Param    page  query int    false "Page number"
+// @Param    size  query int    false "Page size"
+// @Param    order query string false "Order by field"
+// @Param    name  query string false "User name (pattern)"
+// @Param    email query string false "User email (pattern)"
+// @Success  200 {object} []model.UserShort "List of users"
+// @Failure  400 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /users [get]
+func getManyUsers(r UserRouter) gin.HandlerFunc {
+	return r.GetMany
+}
+
+// @Summary  Create new user
+// @ID       create-user
+// @Tags     users
+// @Accept   json
+// @Param    user body model.UserInput true "New user entry"
+// @Success  202 {string} Success message
+// @Failure  400 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /users [post]
+func createUser(r UserRouter) gin.HandlerFunc {
+	return r.Create
+}
+
+// @Summary  Get the single user
+// @ID       single-user
+// @Tags     users
+// @Produce  json
+// @Param    user_name path string true "User name"
+// @Success  201 {object} model.User "Requested user"
+// @Failure  400 {string} Error in request
+// @Failure  404 {string} No record found
+// @Failure  500 {string} Database error
+// @Router   /users/{user_name} [get]
+func getOneUser(r UserRouter) gin.HandlerFunc {
+	return r.GetOne
+}
+
+// @Summary  Update user
+// @ID       update-user
+// @Tags     users
+// @Accept   json
+// @Param    user_name path string          true "User name"
+// @Param    user      body model.UserInput true "Updated user entry"
+// @Success  200 {object} model.User "Updated user"
+// @Failure  400 {string} Error in request
+// @Failure  404 {string} No record found
+// @Failure  500 {string} Database error
+// @Router   /users/{user_name} [put]
+func updateUser(r UserRouter) gin.HandlerFunc {
+	return r.Update
+}
+
+// @Summary  Delete user
+// @ID       delete-user
+// @Tags     users
+// @Param    user_name path string true "User name"
+// @Success  200 {string} Success message
+// @Failure  404 {string} Error in request
+// @Failure  500 {string} Database error
+// @Router   /users/{user_name} [delete]
+func deleteUser(r UserRouter) gin.HandlerFunc {
+	return r.Delete
+}
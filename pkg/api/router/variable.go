@@ -12,6 +12,7 @@ type VariableRouter = IRouter[model.Variable, model.Variable, model.VariableInpu
 
 func InitVariableRouter(base, project, pipeline *gin.RouterGroup) {
 	r := NewRouter[model.Variable, model.Variable, model.VariableInput](
+		Access{Read: model.RoleViewer, Write: model.RoleDeveloper},
 		// FILTER
 		func(ctx *gin.Context) map[string]any {
 			filters := map[string]any{}
@@ -56,7 +57,7 @@ func InitVariableRouter(base, project, pipeline *gin.RouterGroup) {
 	)
 
 	{
-		_rg := base.Group("/variables")
+		_rg := base.Group("/variables", r.Authorize())
 
 		_rg.GET("", getManyVariables(r))
 		_rg.POST("", createVariable(r))
@@ -64,7 +65,7 @@ func InitVariableRouter(base, project, pipeline *gin.RouterGroup) {
 		_rg.DELETE(":variable_key", deleteVariable(r))
 	}
 	{
-		_rg := project.Group(":project_name/variables")
+		_rg := project.Group(":project_name/variables", r.Authorize())
 
 		_rg.GET("", getManyVariables(r))
 		_rg.POST("", createVariable(r))
@@ -72,7 +73,7 @@ func InitVariableRouter(base, project, pipeline *gin.RouterGroup) {
 		_rg.DELETE(":variable_key", deleteVariable(r))
 	}
 	{
-		_rg := pipeline.Group(":pipeline_name/variables")
+		_rg := pipeline.Group(":pipeline_name/variables", r.Authorize())
 
 		_rg.GET("", getManyVariables(r))
 		_rg.POST("", createVariable(r))
@@ -1,8 +1,14 @@
 package router
 
 import (
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"net/http"
+	"strings"
+	"time"
 
+	"github.com/gg-mike/ccli/pkg/api/handler"
 	"github.com/gg-mike/ccli/pkg/model"
 	"github.com/gin-gonic/gin"
 )
@@ -11,6 +17,7 @@ type WorkerRouter = IRouter[model.Worker, model.WorkerShort, model.WorkerInput]
 
 func InitWorkerRouter(base *gin.RouterGroup) {
 	r := NewRouter[model.Worker, model.WorkerShort, model.WorkerInput](
+		Access{Read: model.RoleViewer, Write: model.RoleAdmin},
 		// FILTER
 		func(ctx *gin.Context) map[string]any {
 			filters := map[string]any{}
@@ -20,8 +27,18 @@ func InitWorkerRouter(base *gin.RouterGroup) {
 					filters["name   LIKE ?"] = "%" + ctx.Query(key) + "%"
 				case "system":
 					filters["system LIKE ?"] = "%" + ctx.Query(key) + "%"
+				case "pool":
+					filters["pool IN ?"] = ctx.QueryArray(key)
 				case "status":
 					filters["status IN ?"] = ctx.QueryArray(key)
+				case "label":
+					labels := model.Labels{}
+					for _, label := range ctx.QueryArray(key) {
+						k, v, _ := strings.Cut(label, "=")
+						labels[k] = v
+					}
+					selector, _ := json.Marshal(labels)
+					filters["labels::jsonb @> ?::jsonb"] = string(selector)
 				case "static":
 					_, ok := ctx.GetQuery(key)
 					filters["is_static = ?"] = ok
@@ -47,34 +64,81 @@ func InitWorkerRouter(base *gin.RouterGroup) {
 			left.Name = right.Name
 			left.Address = right.Address
 			left.System = right.System
-			left.IsStatic = right.IsStatic
+			left.IsStatic = right.IsStatic || left.IsAgent
+			left.Type = right.Type
+			left.Local = right.Local
+			left.Cluster = right.Cluster
+			left.Pool = right.Pool
 			left.Strategy = right.Strategy
 			left.Username = right.Username
 			left.Capacity = right.Capacity
+			left.Labels = right.Labels
+			left.Resources = right.Resources
 			return left
 		},
 	)
 
-	_rg := base.Group("/workers")
+	_rg := base.Group("/workers", r.Authorize())
 
 	_rg.GET("", getManyWorkers(r))
 	_rg.POST("", createWorker(r))
 	_rg.GET(":worker_name", getOneWorker(r))
 	_rg.PUT(":worker_name", updateWorker(r))
 	_rg.DELETE(":worker_name", deleteWorker(r))
+	_rg.GET(":worker_name/host-key", getWorkerHostKey())
+	_rg.POST(":worker_name/host-key/confirm", confirmWorkerHostKey())
+	_rg.POST(":worker_name/host-key/rotate", rotateWorkerHostKey())
+	_rg.GET(":worker_name/drain", getWorkerDrain())
+	_rg.POST(":worker_name/drain", drainWorker())
+	_rg.POST(":worker_name/undrain", undrainWorker())
+}
+
+type HostKeyInput struct {
+	Fingerprint string `json:"fingerprint"`
+}
+
+type HostKeyOutput struct {
+	Fingerprint string `json:"fingerprint"`
+	State       string `json:"state"`
+	Key         string `json:"key"`
+}
+
+type DrainInput struct {
+	// Time after which builds still running are canceled (e.g. 30m), none if empty.
+	Timeout string `json:"timeout"`
+}
+
+type DrainOutput struct {
+	Name         string       `json:"name"`
+	Draining     bool         `json:"draining"`
+	DrainBy      sql.NullTime `json:"drain_by"`
+	ActiveBuilds int          `json:"active_builds"`
+	Empty        bool         `json:"empty"`
+}
+
+func newDrainOutput(worker model.Worker) DrainOutput {
+	return DrainOutput{
+		Name:         worker.Name,
+		Draining:     worker.Draining,
+		DrainBy:      worker.DrainBy,
+		ActiveBuilds: worker.ActiveBuilds,
+		Empty:        worker.Drained(),
+	}
 }
 
 // @Summary  Get workers
 // @ID       many-workers
 // @Tags     workers
 // @Produce  json
-// @Param    page      query int    false "Page number"
-// @Param    size      query int    false "Page size"
-// @Param    order     query string false "Order by field"
-// @Param    name      query string false "Worker name (pattern)"
-// @Param    system    query string false "Worker system (pattern)"
-// @Param    status    query []int  false "Worker status (possible values)"
-// @Param    is_static query bool   false "Worker type"
+// @Param    page      query int      false "Page number"
+// @Param    size      query int      false "Page size"
+// @Param    order     query string   false "Order by field"
+// @Param    name      query string   false "Worker name (pattern)"
+// @Param    system    query string   false "Worker system (pattern)"
+// @Param    status    query []int    false "Worker status (possible values)"
+// @Param    pool      query []string false "Worker pool (possible values)"
+// @Param    is_static query bool     false "Worker type"
+// @Param    label     query []string false "Worker label (key=value, all must match)"
 // @Success  200 {object} []model.WorkerShort "List of workers"
 // @Failure  400 {string} Error in request
 // @Failure  500 {string} Database error
@@ -137,3 +201,165 @@ func updateWorker(r WorkerRouter) gin.HandlerFunc {
 func deleteWorker(r WorkerRouter) gin.HandlerFunc {
 	return r.Delete
 }
+
+// @Summary  Get worker host key
+// @ID       worker-host-key
+// @Tags     workers
+// @Produce  json
+// @Param    worker_name path string true "Worker name"
+// @Success  200 {object} HostKeyOutput "Recorded host key"
+// @Failure  404 {string} No record found
+// @Failure  500 {string} Database error
+// @Router   /workers/{worker_name}/host-key [get]
+func getWorkerHostKey() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		worker, err := handler.GetWorker(ctx.Param("worker_name"))
+		switch err {
+		case nil:
+			ctx.JSON(http.StatusOK, HostKeyOutput{Fingerprint: worker.HostKeyFP, State: worker.HostKeyState, Key: worker.HostKey})
+		case handler.ErrRecordNotFound:
+			ctx.String(http.StatusNotFound, "record not found")
+		case handler.ErrDatabase:
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+		}
+	}
+}
+
+// @Summary  Confirm pending worker host key
+// @ID       confirm-worker-host-key
+// @Tags     workers
+// @Accept   json
+// @Param    worker_name path string       true "Worker name"
+// @Param    host_key    body HostKeyInput true "Fingerprint verified out of band"
+// @Success  200 {object} model.Worker "Updated worker"
+// @Failure  404 {string} No record found
+// @Failure  409 {string} Fingerprint mismatch
+// @Failure  500 {string} Database error
+// @Router   /workers/{worker_name}/host-key/confirm [post]
+func confirmWorkerHostKey() gin.HandlerFunc {
+	return hostKeyHandler(handler.ConfirmHostKey)
+}
+
+// @Summary  Rotate worker host key
+// @ID       rotate-worker-host-key
+// @Tags     workers
+// @Accept   json
+// @Param    worker_name path string       true "Worker name"
+// @Param    host_key    body HostKeyInput true "Expected new fingerprint (optional)"
+// @Success  200 {object} model.Worker "Updated worker"
+// @Failure  404 {string} No record found
+// @Failure  409 {string} Fingerprint mismatch
+// @Failure  500 {string} Database error
+// @Router   /workers/{worker_name}/host-key/rotate [post]
+func rotateWorkerHostKey() gin.HandlerFunc {
+	return hostKeyHandler(handler.RotateHostKey)
+}
+
+func hostKeyHandler(update func(actor, workerName, fingerprint string) (model.Worker, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		m := HostKeyInput{}
+		if ctx.Request.ContentLength != 0 {
+			if err := ctx.BindJSON(&m); err != nil {
+				ctx.String(http.StatusBadRequest, "error in json: [%v]", err)
+				return
+			}
+		}
+		worker, err := update(actor(ctx), ctx.Param("worker_name"), m.Fingerprint)
+		switch {
+		case err == nil:
+			ctx.JSON(http.StatusOK, worker)
+		case err == handler.ErrRecordNotFound:
+			ctx.String(http.StatusNotFound, "record not found")
+		case errors.Is(err, handler.ErrConflict):
+			ctx.String(http.StatusConflict, err.Error())
+		default:
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+		}
+	}
+}
+
+// @Summary  Get worker drain status
+// @ID       worker-drain
+// @Tags     workers
+// @Produce  json
+// @Param    worker_name path string true "Worker name"
+// @Success  200 {object} DrainOutput "Drain status"
+// @Failure  404 {string} No record found
+// @Failure  500 {string} Database error
+// @Router   /workers/{worker_name}/drain [get]
+func getWorkerDrain() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		worker, err := handler.GetWorker(ctx.Param("worker_name"))
+		switch err {
+		case nil:
+			ctx.JSON(http.StatusOK, newDrainOutput(worker))
+		case handler.ErrRecordNotFound:
+			ctx.String(http.StatusNotFound, "record not found")
+		case handler.ErrDatabase:
+			ctx.String(http.StatusInternalServerError, "error during database operations")
+		}
+	}
+}
+
+// @Summary  Drain worker
+// @ID       drain-worker
+// @Tags     workers
+// @Accept   json
+// @Produce  json
+// @Param    worker_name path string     true  "Worker name"
+// @Param    drain       body DrainInput false "Drain options"
+// @Success  200 {object} DrainOutput "Drain status"
+// @Failure  400 {string} Error in request
+// @Failure  404 {string} No record found
+// @Failure  500 {string} Database error
+// @Router   /workers/{worker_name}/drain [post]
+func drainWorker() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		m := DrainInput{}
+		if ctx.Request.ContentLength != 0 {
+			if err := ctx.BindJSON(&m); err != nil {
+				ctx.String(http.StatusBadRequest, "error in json: [%v]", err)
+				return
+			}
+		}
+		deadline := sql.NullTime{}
+		if m.Timeout != "" {
+			timeout, err := time.ParseDuration(m.Timeout)
+			if err != nil || timeout <= 0 {
+				ctx.String(http.StatusBadRequest, "invalid timeout [%s]", m.Timeout)
+				return
+			}
+			deadline = sql.NullTime{Time: time.Now().Add(timeout), Valid: true}
+		}
+		drainHandler(ctx, func(actor, workerName string) (model.Worker, error) {
+			return handler.DrainWorker(actor, workerName, deadline)
+		})
+	}
+}
+
+// @Summary  Undrain worker
+// @ID       undrain-worker
+// @Tags     workers
+// @Produce  json
+// @Param    worker_name path string true "Worker name"
+// @Success  200 {object} DrainOutput "Drain status"
+// @Failure  404 {string} No record found
+// @Failure  500 {string} Database error
+// @Router   /workers/{worker_name}/undrain [post]
+func undrainWorker() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		drainHandler(ctx, handler.UndrainWorker)
+	}
+}
+
+func drainHandler(ctx *gin.Context, update func(actor, workerName string) (model.Worker, error)) {
+	worker, err := update(actor(ctx), ctx.Param("worker_name"))
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, newDrainOutput(worker))
+	case err == handler.ErrRecordNotFound:
+		ctx.String(http.StatusNotFound, "record not found")
+	default:
+		ctx.String(http.StatusInternalServerError, "error during database operations")
+	}
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/agent"
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	agentTokenPrefix = "ccli_reg_"
+	agentKeyPrefix   = "ccli_agent_"
+	agentKey         = "agent"
+)
+
+// NewAgentToken creates the one-time registration token and returns its plain
+// value, which is never stored and cannot be retrieved later.
+func NewAgentToken(createdBy, pool string, ttl time.Duration) (string, model.AgentToken, error) {
+	value, err := randomValue(agentTokenPrefix)
+	if err != nil {
+		return "", model.AgentToken{}, err
+	}
+
+	m := model.AgentToken{
+		Hash:      hashToken(value),
+		Pool:      pool,
+		CreatedBy: createdBy,
+	}
+	if ttl > 0 {
+		m.ExpiresAt = sql.NullTime{Time: time.Now().Add(ttl), Valid: true}
+	}
+
+	if err := db.Get().Create(&m).Error; err != nil {
+		return "", model.AgentToken{}, err
+	}
+	return value, m, nil
+}
+
+// RegisterAgent consumes the registration token, creates the agent worker
+// and issues the key the agent connects with from now on.
+func RegisterAgent(registration agent.Registration) (agent.Credentials, model.Worker, error) {
+	key, err := randomValue(agentKeyPrefix)
+	if err != nil {
+		return agent.Credentials{}, model.Worker{}, err
+	}
+
+	var worker model.Worker
+	err = db.Get().Transaction(func(tx *gorm.DB) error {
+		// Claiming with a conditional update lets only one of concurrent
+		// registrations consume the token.
+		now := time.Now()
+		result := tx.Model(&model.AgentToken{}).
+			Where("hash = ? AND used_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", hashToken(registration.Token), now).
+			UpdateColumn("used_at", sql.NullTime{Time: now, Valid: true})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrInvalidToken
+		}
+		var token model.AgentToken
+		if err := tx.Where(&model.AgentToken{Hash: hashToken(registration.Token)}).First(&token).Error; err != nil {
+			return err
+		}
+		// Token scoped to a pool decides where the agent gets jobs from.
+		pool := registration.Pool
+		if token.Pool != "" {
+			if pool != "" && pool != token.Pool {
+				return ErrPoolMismatch
+			}
+			pool = token.Pool
+		}
+
+		worker = model.Worker{
+			Name:         registration.Name,
+			System:       registration.System,
+			IsStatic:     true,
+			IsAgent:      true,
+			AgentKey:     hashToken(key),
+			Pool:         pool,
+			Labels:       registration.Labels,
+			Capacity:     registration.Capacity,
+			Status:       model.WorkerUnreachable,
+			StatusReason: agent.ErrNotConnected.Error(),
+		}
+		if err := tx.Create(&worker).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&token).UpdateColumn("worker_name", worker.Name).Error
+	})
+	if err != nil {
+		return agent.Credentials{}, model.Worker{}, err
+	}
+	return agent.Credentials{Name: worker.Name, Key: key}, worker, nil
+}
+
+// AuthenticateAgent resolves the bearer key of the request into the name of
+// the agent worker it was issued for.
+func AuthenticateAgent() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !ok || key == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		var worker model.Worker
+		if err := db.Get().Where(&model.Worker{AgentKey: hashToken(key), IsAgent: true}).First(&worker).Error; err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, ErrInvalidToken.Error())
+			return
+		}
+		ctx.Set(agentKey, worker.Name)
+	}
+}
+
+func GetAgent(ctx *gin.Context) string {
+	return ctx.GetString(agentKey)
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gin-gonic/gin"
+)
+
+type Config struct {
+	Enabled   bool
+	RootToken string
+	Admins    []string
+	OIDC      OIDCConfig
+}
+
+type Principal struct {
+	Name   string
+	IsRoot bool
+}
+
+const principalKey = "principal"
+
+var config *Config
+
+func Get() *Config {
+	if config == nil {
+		panic("auth is not initialized")
+	}
+	return config
+}
+
+func Init(c Config) error {
+	if config != nil {
+		panic("auth is already initialized")
+	}
+
+	config = &c
+	if c.OIDC.Issuer != "" {
+		return initOIDC(c.OIDC)
+	}
+	return nil
+}
+
+// Authenticate resolves the bearer token of the request into a principal.
+// With authentication disabled every request is treated as the root user.
+func Authenticate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !Get().Enabled {
+			ctx.Set(principalKey, Principal{Name: "anonymous", IsRoot: true})
+			return
+		}
+
+		token, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		principal, err := principalFromToken(token)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, err.Error())
+			return
+		}
+		ctx.Set(principalKey, principal)
+	}
+}
+
+// Require aborts requests whose principal does not hold at least the given
+// role, either globally or for the project addressed by the request path.
+func Require(role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, ok := GetPrincipal(ctx)
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, "request is not authenticated")
+			return
+		}
+
+		allowed, err := Allowed(principal, ctx.Param("project_name"), role)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, "error during database operations")
+			return
+		}
+		if !allowed {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, "role ["+role+"] is required")
+			return
+		}
+	}
+}
+
+func GetPrincipal(ctx *gin.Context) (Principal, bool) {
+	principal, ok := ctx.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	return principal.(Principal), true
+}
+
+func Allowed(principal Principal, projectName, role string) (bool, error) {
+	if principal.IsRoot {
+		return true, nil
+	}
+	granted, err := RoleOf(principal.Name, projectName)
+	if err != nil {
+		return false, err
+	}
+	return model.RoleRank(granted) >= model.RoleRank(role), nil
+}
+
+// RoleOf returns the highest role bound to the user, directly or through
+// one of their teams, either globally or for the given project.
+func RoleOf(userName, projectName string) (string, error) {
+	var teams []string
+	if err := db.Get().Table("team_members").Where("user_name = ?", userName).Pluck("team_name", &teams).Error; err != nil {
+		return "", err
+	}
+
+	query := db.Get().Where("user_name = ?", userName)
+	if len(teams) != 0 {
+		query = db.Get().Where(query.Or("team_name IN ?", teams))
+	}
+	if projectName == "" {
+		query = query.Where("project_name IS NULL")
+	} else {
+		query = query.Where("project_name IS NULL OR project_name = ?", projectName)
+	}
+
+	var bindings []model.RoleBinding
+	if err := query.Find(&bindings).Error; err != nil {
+		return "", err
+	}
+
+	role := ""
+	for _, binding := range bindings {
+		if model.RoleRank(binding.Role) > model.RoleRank(role) {
+			role = binding.Role
+		}
+	}
+	return role, nil
+}
+
+func principalFromToken(token string) (Principal, error) {
+	if Get().RootToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(Get().RootToken)) == 1 {
+		return Principal{Name: "root", IsRoot: true}, nil
+	}
+
+	var m model.Token
+	if err := db.Get().Where(&model.Token{Hash: hashToken(token)}).First(&m).Error; err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	if m.Expired() {
+		return Principal{}, ErrExpiredToken
+	}
+
+	db.Get().Model(&m).UpdateColumn("last_used_at", sql.NullTime{Time: time.Now(), Valid: true})
+
+	return Principal{Name: m.UserName}, nil
+}
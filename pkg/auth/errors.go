@@ -0,0 +1,11 @@
+package auth
+
+import "errors"
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token expired")
+	ErrInvalidState = errors.New("invalid login state")
+	ErrOIDCDisabled = errors.New("OIDC login is not configured")
+	ErrPoolMismatch = errors.New("registration token is scoped to another pool")
+)
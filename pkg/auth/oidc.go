@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/model"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	TokenTTL     time.Duration
+}
+
+type discovery struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+type userInfo struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+var (
+	oauthConfig      *oauth2.Config
+	issuer           string
+	userinfoEndpoint string
+	tokenTTL         time.Duration
+)
+
+func initOIDC(c OIDCConfig) error {
+	url := strings.TrimSuffix(c.Issuer, "/") + "/.well-known/openid-configuration"
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OIDC discovery returned status %d", resp.StatusCode)
+	}
+
+	var d discovery
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return err
+	}
+
+	oauthConfig = &oauth2.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		RedirectURL:  c.RedirectURL,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  d.AuthorizationEndpoint,
+			TokenURL: d.TokenEndpoint,
+		},
+		Scopes: []string{"openid", "email", "profile"},
+	}
+	issuer = c.Issuer
+	userinfoEndpoint = d.UserinfoEndpoint
+	tokenTTL = c.TokenTTL
+	if tokenTTL == 0 {
+		tokenTTL = 24 * time.Hour
+	}
+	return nil
+}
+
+// LoginURL returns the provider URL the user should be redirected to together
+// with the state value that has to be presented back on callback.
+func LoginURL() (string, string, error) {
+	if oauthConfig == nil {
+		return "", "", ErrOIDCDisabled
+	}
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	state := hex.EncodeToString(raw)
+	return oauthConfig.AuthCodeURL(state), state, nil
+}
+
+// Callback exchanges the authorization code, provisions the user on first
+// login and issues a session token for the API.
+func Callback(ctx context.Context, code string) (string, model.Token, error) {
+	if oauthConfig == nil {
+		return "", model.Token{}, ErrOIDCDisabled
+	}
+	token, err := oauthConfig.Exchange(ctx, code)
+	if err != nil {
+		return "", model.Token{}, err
+	}
+
+	resp, err := oauthConfig.Client(ctx, token).Get(userinfoEndpoint)
+	if err != nil {
+		return "", model.Token{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", model.Token{}, fmt.Errorf("OIDC userinfo returned status %d", resp.StatusCode)
+	}
+
+	var info userInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", model.Token{}, err
+	}
+
+	user, err := provisionUser(info)
+	if err != nil {
+		return "", model.Token{}, err
+	}
+
+	return NewToken("oidc-session", user.Name, tokenTTL)
+}
+
+// provisionUser returns the user bound to the identity (issuer and subject),
+// creating one on first login. Username and email may be changed at the
+// provider, so they only name the new user and never match existing ones.
+func provisionUser(info userInfo) (model.User, error) {
+	if info.Subject == "" {
+		return model.User{}, errors.New("OIDC userinfo does not identify the user")
+	}
+	email := ""
+	if info.EmailVerified {
+		email = info.Email
+	}
+
+	var user model.User
+	err := db.Get().Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("oidc_issuer = ? AND oidc_subject = ?", issuer, info.Subject).First(&user).Error
+		switch {
+		case err == nil:
+			if err := tx.Model(&user).UpdateColumns(map[string]any{"email": email, "updated_at": time.Now()}).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if user, err = createOIDCUser(tx, info, email); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		if !isAdmin(Get().Admins, info) {
+			return nil
+		}
+		binding := model.RoleBinding{}
+		return tx.Where("user_name = ? AND project_name IS NULL AND role = ?", user.Name, model.RoleAdmin).
+			Attrs(model.RoleBinding{
+				UserName: sqlString(user.Name),
+				Role:     model.RoleAdmin,
+			}).
+			FirstOrCreate(&binding).Error
+	})
+	return user, err
+}
+
+// createOIDCUser creates user of the identity named after its username or
+// email, falling back to name derived from the identity when it is taken.
+func createOIDCUser(tx *gorm.DB, info userInfo, email string) (model.User, error) {
+	user := model.User{
+		Email:       email,
+		OIDCIssuer:  sqlString(issuer),
+		OIDCSubject: sqlString(info.Subject),
+	}
+	for _, name := range []string{info.PreferredUsername, email} {
+		if name == "" {
+			continue
+		}
+		user.Name = name
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&user)
+		if result.Error != nil {
+			return model.User{}, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return user, nil
+		}
+	}
+	sum := sha256.Sum256([]byte(issuer + "\x00" + info.Subject))
+	user.Name = "oidc-" + hex.EncodeToString(sum[:6])
+	return user, tx.Create(&user).Error
+}
+
+// isAdmin reports whether the identity is listed in admins by its subject or
+// verified email.
+func isAdmin(admins []string, info userInfo) bool {
+	return slices.Contains(admins, info.Subject) ||
+		(info.EmailVerified && info.Email != "" && slices.Contains(admins, info.Email))
+}
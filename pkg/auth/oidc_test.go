@@ -0,0 +1,25 @@
+package auth
+
+import "testing"
+
+func TestIsAdmin(t *testing.T) {
+	admins := []string{"alice@example.com", "0f3a9c"}
+	tests := []struct {
+		name string
+		info userInfo
+		want bool
+	}{
+		{"verified email", userInfo{Subject: "1", Email: "alice@example.com", EmailVerified: true}, true},
+		{"unverified email", userInfo{Subject: "1", Email: "alice@example.com"}, false},
+		{"subject", userInfo{Subject: "0f3a9c"}, true},
+		{"username", userInfo{Subject: "1", PreferredUsername: "alice@example.com"}, false},
+		{"other user", userInfo{Subject: "2", Email: "bob@example.com", EmailVerified: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAdmin(admins, tt.info); got != tt.want {
+				t.Errorf("isAdmin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
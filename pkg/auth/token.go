@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/model"
+)
+
+const tokenPrefix = "ccli_"
+
+// NewToken creates a token for the user and returns its plain value, which is
+// never stored and cannot be retrieved later.
+func NewToken(name, userName string, ttl time.Duration) (string, model.Token, error) {
+	value, err := randomValue(tokenPrefix)
+	if err != nil {
+		return "", model.Token{}, err
+	}
+
+	m := model.Token{
+		Name:     name,
+		UserName: userName,
+		Hash:     hashToken(value),
+	}
+	if ttl > 0 {
+		m.ExpiresAt = sql.NullTime{Time: time.Now().Add(ttl), Valid: true}
+	}
+
+	if err := db.Get().Create(&m).Error; err != nil {
+		return "", model.Token{}, err
+	}
+	return value, m, nil
+}
+
+func randomValue(prefix string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(raw), nil
+}
+
+func hashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func sqlString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
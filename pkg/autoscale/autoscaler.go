@@ -0,0 +1,240 @@
+package autoscale
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/log"
+	"github.com/gg-mike/ccli/pkg/model"
+)
+
+type Config struct {
+	Pool              string
+	Min               int
+	Max               int
+	Capacity          int
+	Interval          time.Duration
+	ScaleUpWait       time.Duration
+	ScaleUpCooldown   time.Duration
+	ScaleDownCooldown time.Duration
+	IdleTimeout       time.Duration
+}
+
+// Autoscaler keeps the pool of ephemeral Docker workers between its minimal
+// and maximal size, following the demand of builds waiting in the queue.
+type Autoscaler struct {
+	config      Config
+	provisioner Provisioner
+	shutdown    chan any
+	done        chan any
+
+	lastScaleUp   time.Time
+	lastScaleDown time.Time
+	idleSince     map[string]time.Time
+
+	logger log.Logger
+}
+
+func NewAutoscaler(logger log.Logger, config Config, provisioner Provisioner) *Autoscaler {
+	if config.Capacity < 1 {
+		config.Capacity = 1
+	}
+	if config.Min > config.Max {
+		config.Min = config.Max
+	}
+	return &Autoscaler{
+		config:      config,
+		provisioner: provisioner,
+		shutdown:    make(chan any),
+		done:        make(chan any),
+		idleSince:   map[string]time.Time{},
+
+		logger: logger.NewComponentLogger("autoscaler"),
+	}
+}
+
+func (a *Autoscaler) Run() {
+	if a.config.Max <= 0 || a.config.Interval <= 0 {
+		a.logger.Info().Msg("autoscaler disabled")
+		close(a.done)
+		return
+	}
+
+	a.logger.Info().Str("pool", a.config.Pool).Int("min", a.config.Min).Int("max", a.config.Max).Msg("starting autoscaler")
+	ticker := time.NewTicker(a.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.scale(time.Now()); err != nil {
+				a.logger.Error().Err(err).Msg("scaling ended with error")
+			}
+		case <-a.shutdown:
+			close(a.done)
+			a.logger.Info().Msg("autoscaler shutdown")
+			return
+		}
+	}
+}
+
+// Restore reconnects to hosts of ephemeral workers created before the server
+// restarted.
+func (a *Autoscaler) Restore() error {
+	var workers []model.Worker
+	if err := db.Get().Where(&model.Worker{Ephemeral: true}).Find(&workers).Error; err != nil {
+		return err
+	}
+	for _, worker := range workers {
+		if err := a.provisioner.Restore(worker.Name, worker.Address); err != nil {
+			a.logger.Error().Str("name", worker.Name).Err(err).Msg("could not restore worker connection")
+		}
+	}
+	return nil
+}
+
+func (a *Autoscaler) Shutdown() chan any {
+	go func() { a.shutdown <- true }()
+	return a.done
+}
+
+func (a *Autoscaler) scale(now time.Time) error {
+	var workers []model.Worker
+	if err := db.Get().Where(&model.Worker{Pool: a.config.Pool, Ephemeral: true}).Find(&workers).Error; err != nil {
+		return err
+	}
+	var queue []model.QueueElem
+	if err := db.Get().Find(&queue).Error; err != nil {
+		return err
+	}
+
+	demand, oldest := a.demand(queue)
+	free := 0
+	for _, worker := range workers {
+		if worker.Status != model.WorkerUnreachable && !worker.Draining {
+			free += worker.Capacity - worker.ActiveBuilds
+		}
+	}
+	size := len(workers)
+
+	switch {
+	case size < a.config.Min:
+		return a.scaleUp(now, a.config.Min-size)
+	case demand > 0 && size < a.config.Max && now.Sub(a.lastScaleUp) >= a.config.ScaleUpCooldown &&
+		(demand > free || now.Sub(oldest) >= a.config.ScaleUpWait):
+		n := (demand - free + a.config.Capacity - 1) / a.config.Capacity
+		return a.scaleUp(now, min(max(n, 1), a.config.Max-size))
+	case demand == 0:
+		return a.scaleDown(now, workers)
+	}
+	return nil
+}
+
+// demand returns the number of queued builds an ephemeral worker could take
+// with the time the oldest of them was queued.
+func (a *Autoscaler) demand(queue []model.QueueElem) (int, time.Time) {
+	demand, oldest := 0, time.Now()
+	for _, elem := range queue {
+		config := elem.Context.Config
+		if config.Image == "" || !config.RunsOn.Matches(model.Labels{}) {
+			continue
+		}
+		demand++
+		if elem.CreatedAt.Before(oldest) {
+			oldest = elem.CreatedAt
+		}
+	}
+	return demand, oldest
+}
+
+func (a *Autoscaler) scaleUp(now time.Time, n int) error {
+	a.lastScaleUp = now
+	for i := 0; i < n; i++ {
+		name, err := a.workerName()
+		if err != nil {
+			return err
+		}
+		a.logger.Info().Str("name", name).Msg("provisioning worker")
+		address, err := a.provisioner.Provision(name)
+		if err != nil {
+			return err
+		}
+		worker := model.Worker{
+			Name:      name,
+			Address:   address,
+			Pool:      a.config.Pool,
+			Capacity:  a.config.Capacity,
+			Ephemeral: true,
+		}
+		if err := db.Get().Create(&worker).Error; err != nil {
+			a.provisioner.Terminate(name, address)
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Autoscaler) scaleDown(now time.Time, workers []model.Worker) error {
+	seen := map[string]bool{}
+	idle := []model.Worker{}
+	for _, worker := range workers {
+		seen[worker.Name] = true
+		if worker.ActiveBuilds > 0 {
+			delete(a.idleSince, worker.Name)
+			continue
+		}
+		since, ok := a.idleSince[worker.Name]
+		if !ok {
+			a.idleSince[worker.Name] = now
+			continue
+		}
+		if now.Sub(since) >= a.config.IdleTimeout {
+			idle = append(idle, worker)
+		}
+	}
+	for name := range a.idleSince {
+		if !seen[name] {
+			delete(a.idleSince, name)
+		}
+	}
+
+	if len(idle) == 0 || now.Sub(a.lastScaleDown) < a.config.ScaleDownCooldown {
+		return nil
+	}
+	a.lastScaleDown = now
+	for _, worker := range idle[:min(len(idle), len(workers)-a.config.Min)] {
+		if err := a.terminate(worker); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// terminate drains the worker first, so that binder cannot pick it while it
+// is being removed.
+func (a *Autoscaler) terminate(worker model.Worker) error {
+	result := db.Get().Model(&model.Worker{}).
+		Where("name = ? AND active_builds = 0", worker.Name).
+		UpdateColumn("draining", true)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	a.logger.Info().Str("name", worker.Name).Msg("terminating idle worker")
+	if err := db.Get().Delete(&worker).Error; err != nil {
+		db.Get().Model(&worker).UpdateColumn("draining", false)
+		return err
+	}
+	delete(a.idleSince, worker.Name)
+	return a.provisioner.Terminate(worker.Name, worker.Address)
+}
+
+func (a *Autoscaler) workerName() (string, error) {
+	raw := make([]byte, 4)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return a.config.Pool + "-" + hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,69 @@
+package autoscale
+
+import (
+	"encoding/json"
+
+	"github.com/gg-mike/ccli/pkg/docker"
+	"github.com/gg-mike/ccli/pkg/vault"
+)
+
+// Provisioner creates and destroys hosts backing ephemeral Docker workers.
+type Provisioner interface {
+	// Provision starts the host for the worker and returns its Docker address.
+	Provision(name string) (string, error)
+	// Restore reconnects to the host provisioned before the server restarted.
+	Restore(name, address string) error
+	Terminate(name, address string) error
+}
+
+// DockerProvisioner runs every worker as Docker-in-Docker container on the
+// single Docker host. Daemons accept only TLS clients, their client
+// certificates are kept in the vault.
+type DockerProvisioner struct {
+	Host    string
+	Image   string
+	Network string
+}
+
+func (p DockerProvisioner) Provision(name string) (string, error) {
+	address, certs, err := docker.StartDaemon(p.Host, name, p.Image, p.Network)
+	if err != nil {
+		return "", err
+	}
+	value, err := json.Marshal(certs)
+	if err == nil {
+		err = vault.SetStr(certificatesKey(name), string(value))
+	}
+	if err == nil {
+		err = docker.SetTLS(address, certs)
+	}
+	if err != nil {
+		docker.StopDaemon(p.Host, name)
+		return "", err
+	}
+	return address, nil
+}
+
+func (p DockerProvisioner) Restore(name, address string) error {
+	value, err := vault.GetStr(certificatesKey(name))
+	if err != nil {
+		return err
+	}
+	var certs docker.TLS
+	if err := json.Unmarshal([]byte(value), &certs); err != nil {
+		return err
+	}
+	return docker.SetTLS(address, certs)
+}
+
+func (p DockerProvisioner) Terminate(name, address string) error {
+	docker.DeleteTLS(address)
+	if err := vault.Del(certificatesKey(name)); err != nil {
+		return err
+	}
+	return docker.StopDaemon(p.Host, name)
+}
+
+func certificatesKey(name string) string {
+	return "dind-tls/" + name
+}
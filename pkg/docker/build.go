@@ -0,0 +1,109 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/gg-mike/ccli/pkg/runner"
+)
+
+// imageBuilder builds images on the daemon running the build container.
+type imageBuilder struct {
+	cli      *client.Client
+	registry Registry
+}
+
+func (b imageBuilder) BuildImage(spec runner.ImageSpec, buildContext io.Reader, out func(string)) (string, error) {
+	args := map[string]*string{}
+	for key, value := range spec.BuildArgs {
+		value := value
+		args[key] = &value
+	}
+	resp, err := b.cli.ImageBuild(context.Background(), buildContext, types.ImageBuildOptions{
+		Tags:        spec.Tags,
+		Dockerfile:  spec.Dockerfile,
+		BuildArgs:   args,
+		Labels:      spec.Labels,
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var built types.BuildResult
+	err = readMessages(resp.Body, out, func(aux json.RawMessage) {
+		json.Unmarshal(aux, &built)
+	})
+	if err != nil {
+		return "", fmt.Errorf("building image: %w", err)
+	}
+	if !spec.Push {
+		return built.ID, nil
+	}
+
+	digest := ""
+	for _, tag := range spec.Tags {
+		auth, err := b.registry.authFor(tag)
+		if err != nil {
+			return "", err
+		}
+		out("pushing image [" + tag + "]")
+		body, err := b.cli.ImagePush(context.Background(), tag, types.ImagePushOptions{RegistryAuth: auth})
+		if err != nil {
+			return "", err
+		}
+		var pushed types.PushResult
+		err = readMessages(body, out, func(aux json.RawMessage) {
+			json.Unmarshal(aux, &pushed)
+		})
+		body.Close()
+		if err != nil {
+			return "", fmt.Errorf("pushing image [%s]: %w", tag, err)
+		}
+		digest = pushed.Digest
+	}
+	return digest, nil
+}
+
+// readMessages passes progress of the daemon operation to report and aux
+// messages to aux until the stream ends or reports an error.
+func readMessages(body io.Reader, report func(string), aux func(json.RawMessage)) error {
+	decoder := json.NewDecoder(body)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Error != nil {
+			return errors.New(msg.Error.Message)
+		}
+		if msg.Aux != nil {
+			aux(*msg.Aux)
+			continue
+		}
+		// Skip byte counters, layer state changes are enough.
+		if msg.Progress != nil && msg.Progress.Total != 0 {
+			continue
+		}
+		if msg.Stream != "" {
+			for _, line := range strings.Split(strings.TrimRight(msg.Stream, "\n"), "\n") {
+				report(line)
+			}
+		} else if msg.ID != "" {
+			report(msg.ID + ": " + msg.Status)
+		} else if msg.Status != "" {
+			report(msg.Status)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+)
+
+const (
+	daemonPort         = "2376"
+	daemonCertDir      = "/certs"
+	daemonReadyTimeout = time.Minute
+)
+
+var ErrDaemonNotReady = errors.New("docker daemon did not become ready in time")
+
+// StartDaemon runs Docker-in-Docker container named after the worker on the
+// host and returns address of its daemon once it responds. The daemon accepts
+// only TLS connections authenticated with the returned client certificate,
+// which it generates on start.
+func StartDaemon(host, name, imageName, networkName string) (string, TLS, error) {
+	conn, err := newClient(host)
+	if err != nil {
+		return "", TLS{}, err
+	}
+	defer conn.client.Close()
+	cli := conn.client
+
+	if err := pullImage(cli, imageName); err != nil {
+		return "", TLS{}, err
+	}
+
+	var networking *network.NetworkingConfig
+	if networkName != "" {
+		networking = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{networkName: {}},
+		}
+	}
+
+	resp, err := cli.ContainerCreate(context.Background(), &container.Config{
+		Image:  imageName,
+		Env:    []string{"DOCKER_TLS_CERTDIR=" + daemonCertDir},
+		Labels: map[string]string{"ccli.worker": name},
+	},
+		&container.HostConfig{
+			AutoRemove: true,
+			Privileged: true,
+		}, networking, nil, name)
+	if err != nil {
+		return "", TLS{}, err
+	}
+
+	if err := cli.ContainerStart(context.Background(), resp.ID, types.ContainerStartOptions{}); err != nil {
+		cli.ContainerRemove(context.Background(), resp.ID, types.ContainerRemoveOptions{Force: true})
+		return "", TLS{}, err
+	}
+
+	info, err := cli.ContainerInspect(context.Background(), resp.ID)
+	if err != nil {
+		return "", TLS{}, err
+	}
+	ip := info.NetworkSettings.IPAddress
+	if endpoint, ok := info.NetworkSettings.Networks[networkName]; ok {
+		ip = endpoint.IPAddress
+	}
+	address := "tcp://" + ip + ":" + daemonPort
+
+	for deadline := time.Now().Add(daemonReadyTimeout); time.Now().Before(deadline); time.Sleep(2 * time.Second) {
+		certs, err := readCertificates(cli, resp.ID, daemonCertDir+"/client")
+		if err != nil {
+			continue
+		}
+		if err := pingDaemon(address, certs); err == nil {
+			return address, certs, nil
+		}
+	}
+	StopDaemon(host, name)
+	return "", TLS{}, ErrDaemonNotReady
+}
+
+func pingDaemon(address string, certs TLS) error {
+	daemon, err := newTLSClient(address, certs)
+	if err != nil {
+		return err
+	}
+	defer daemon.client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = daemon.client.Ping(ctx)
+	return err
+}
+
+func StopDaemon(host, name string) error {
+	conn, err := newClient(host)
+	if err != nil {
+		return err
+	}
+	defer conn.client.Close()
+
+	return conn.client.ContainerRemove(context.Background(), name, types.ContainerRemoveOptions{Force: true})
+}
@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+)
+
+const (
+	PullAlways       = "always"
+	PullIfNotPresent = "if-not-present"
+	PullNever        = "never"
+)
+
+// Registry holds credentials which are sent only with pulls of images hosted
+// on the server.
+type Registry struct {
+	Server   string
+	Username string
+	Password string
+}
+
+func (r Registry) authFor(imageName string) (string, error) {
+	if r.Server == "" || registryHost(imageName) != registryHost(r.Server+"/") {
+		return "", nil
+	}
+	encoded, err := json.Marshal(registry.AuthConfig{
+		Username:      r.Username,
+		Password:      r.Password,
+		ServerAddress: r.Server,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(encoded), nil
+}
+
+// registryHost returns the registry part of the image reference, images
+// without one come from Docker Hub.
+func registryHost(imageName string) string {
+	imageName = strings.TrimPrefix(strings.TrimPrefix(imageName, "https://"), "http://")
+	host, _, found := strings.Cut(imageName, "/")
+	if !found || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		return "docker.io"
+	}
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		return "docker.io"
+	}
+	return host
+}
+
+// ensureImage makes the image available on the host according to the pull
+// policy, pull progress is passed to report.
+func ensureImage(cli *client.Client, imageName, policy string, registry Registry, report func(string)) error {
+	if policy == PullNever || policy == PullIfNotPresent {
+		_, _, err := cli.ImageInspectWithRaw(context.Background(), imageName)
+		if err == nil {
+			report("image [" + imageName + "] present on the host")
+			return nil
+		}
+		if !client.IsErrNotFound(err) {
+			return err
+		}
+		if policy == PullNever {
+			return fmt.Errorf("image [%s] not present on the host and pull_policy is never", imageName)
+		}
+	}
+
+	auth, err := registry.authFor(imageName)
+	if err != nil {
+		return err
+	}
+	report("pulling image [" + imageName + "]")
+	out, err := cli.ImagePull(context.Background(), imageName, types.ImagePullOptions{RegistryAuth: auth})
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := readMessages(out, report, func(json.RawMessage) {}); err != nil {
+		return fmt.Errorf("pulling image [%s]: %w", imageName, err)
+	}
+	return nil
+}
+
+func pullImage(cli *client.Client, imageName string) error {
+	return ensureImage(cli, imageName, PullAlways, Registry{}, func(string) {})
+}
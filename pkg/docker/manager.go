@@ -1,15 +1,19 @@
 package docker
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gg-mike/ccli/pkg/runner"
 )
 
 type Manager struct {
+	mu      sync.Mutex
 	clients map[string]*Client
+	tls     map[string]TLS
 }
 
 var manager *Manager
@@ -28,39 +32,80 @@ func Init() error {
 
 	manager = &Manager{
 		clients: map[string]*Client{},
+		tls:     map[string]TLS{},
 	}
 
 	return nil
 }
 
 func NewClient(host string) error {
-	conn, err := newClient(host)
+	m := Get()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conn, err := m.connect(host)
 	if err != nil {
 		return err
 	}
-	Get().clients[host] = conn
+	m.clients[host] = conn
 	return nil
 }
 
 func DeleteClient(host string) error {
-	if err := Get().clients[host].client.Close(); err != nil {
+	m := Get()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conn, ok := m.clients[host]
+	if !ok {
+		return nil
+	}
+	if err := conn.client.Close(); err != nil {
 		return err
 	}
-	delete(Get().clients, host)
+	delete(m.clients, host)
 	return nil
 }
 
-func NewRunner(host, imageName string, privileged bool) (*runner.Runner, error) {
-	conn, ok := Get().clients[host]
-	if !ok {
-		var err error
-		conn, err = newClient(host)
-		if err != nil {
-			return &runner.Runner{}, err
-		}
-		Get().clients[host] = conn
-	}
-	_runner, err := newRunner(conn.client, imageName, privileged)
+// getClient returns the client of the host, connecting on first use.
+func getClient(host string) (*Client, error) {
+	m := Get()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if conn, ok := m.clients[host]; ok {
+		return conn, nil
+	}
+	conn, err := m.connect(host)
+	if err != nil {
+		return nil, err
+	}
+	m.clients[host] = conn
+	return conn, nil
+}
+
+// connect creates client of the host, using TLS when it was set for it.
+func (m *Manager) connect(host string) (*Client, error) {
+	if t, ok := m.tls[host]; ok {
+		return newTLSClient(host, t)
+	}
+	return newClient(host)
+}
+
+func Ping(host string) error {
+	conn, err := getClient(host)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = conn.client.Ping(ctx)
+	return err
+}
+
+func NewRunner(host string, config RunnerConfig) (*runner.Runner, error) {
+	conn, err := getClient(host)
+	if err != nil {
+		return &runner.Runner{}, err
+	}
+	_runner, err := newRunner(conn.client, config)
 	if err != nil {
 		return &runner.Runner{}, err
 	}
@@ -68,14 +113,17 @@ func NewRunner(host, imageName string, privileged bool) (*runner.Runner, error)
 }
 
 func Shutdown() error {
-	if len(Get().clients) == 0 {
+	m := Get()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.clients) == 0 {
 		return nil
 	}
 
 	var wg sync.WaitGroup
-	errChan := make(chan error, len(Get().clients))
+	errChan := make(chan error, len(m.clients))
 
-	for _, cli := range Get().clients {
+	for _, cli := range m.clients {
 		wg.Add(1)
 		go cli.Shutdown(errChan, &wg)
 	}
@@ -0,0 +1,81 @@
+package docker
+
+import (
+	"context"
+	"errors"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// Labels put on containers and networks created for builds.
+const (
+	LabelBuild    = "ccli.build"
+	LabelInstance = "ccli.instance"
+	LabelService  = "ccli.service"
+)
+
+func BuildLabels(instance, buildID string) map[string]string {
+	return map[string]string{LabelInstance: instance, LabelBuild: buildID}
+}
+
+// RemoveOrphans removes containers, networks and workspace volumes created for
+// builds on the host unless keep says they are still in use, returns how many
+// were removed.
+func RemoveOrphans(host string, keep func(buildID, instance string) bool) (int, error) {
+	conn, err := getClient(host)
+	if err != nil {
+		return 0, err
+	}
+	cli := conn.client
+	ctx := context.Background()
+	selector := filters.NewArgs(filters.Arg("label", LabelBuild))
+
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: selector})
+	if err != nil {
+		return 0, err
+	}
+	removed, errs := 0, []error{}
+	for _, c := range containers {
+		if keep(c.Labels[LabelBuild], c.Labels[LabelInstance]) {
+			continue
+		}
+		if err := cli.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{Force: true}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+
+	networks, err := cli.NetworkList(ctx, types.NetworkListOptions{Filters: selector})
+	if err != nil {
+		return removed, errors.Join(append(errs, err)...)
+	}
+	for _, n := range networks {
+		if keep(n.Labels[LabelBuild], n.Labels[LabelInstance]) {
+			continue
+		}
+		if err := cli.NetworkRemove(ctx, n.ID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+
+	volumes, err := cli.VolumeList(ctx, volume.ListOptions{Filters: selector})
+	if err != nil {
+		return removed, errors.Join(append(errs, err)...)
+	}
+	for _, v := range volumes.Volumes {
+		if keep(v.Labels[LabelBuild], v.Labels[LabelInstance]) {
+			continue
+		}
+		if err := cli.VolumeRemove(ctx, v.Name, true); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+	return removed, errors.Join(errs...)
+}
@@ -1,8 +1,9 @@
 package docker
 
 import (
-	"bufio"
 	"context"
+	"errors"
+	"strconv"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
@@ -10,34 +11,79 @@ import (
 	"github.com/gg-mike/ccli/pkg/runner"
 )
 
-func newRunner(cli *client.Client, imageName string, privileged bool) (*runner.Runner, error) {
-	out, err := cli.ImagePull(context.Background(), imageName, types.ImagePullOptions{})
-	if err != nil {
+// Resources of the build container, CPU in millicores and the rest in bytes,
+// zero values are not constrained.
+type Resources struct {
+	CPURequest       int64
+	CPULimit         int64
+	MemoryRequest    int64
+	MemoryLimit      int64
+	EphemeralStorage int64
+}
+
+func (r Resources) apply(hostConfig *container.HostConfig) {
+	hostConfig.NanoCPUs = r.CPULimit * 1e6
+	if r.CPURequest != 0 {
+		// Same conversion as kubelet does for cpu requests.
+		hostConfig.CPUShares = max(r.CPURequest*1024/1000, 2)
+	}
+	hostConfig.Memory = r.MemoryLimit
+	hostConfig.MemoryReservation = r.MemoryRequest
+	if r.EphemeralStorage != 0 {
+		// Supported only by some storage drivers (e.g. overlay2 on xfs with pquota).
+		hostConfig.StorageOpt = map[string]string{"size": strconv.FormatInt(r.EphemeralStorage, 10)}
+	}
+}
+
+// RunnerConfig describes the build container and everything started for it.
+type RunnerConfig struct {
+	Image      string
+	Privileged bool
+	PullPolicy string
+	Registry   Registry
+	Resources  Resources
+	Services   []Service
+	Workspace  Workspace
+	Labels     map[string]string
+	// Report receives progress of image pulls and services startup.
+	Report func(string)
+}
+
+func newRunner(cli *client.Client, config RunnerConfig) (*runner.Runner, error) {
+	if config.Report == nil {
+		config.Report = func(string) {}
+	}
+	if err := ensureImage(cli, config.Image, config.PullPolicy, config.Registry, config.Report); err != nil {
 		return &runner.Runner{}, err
 	}
-	defer out.Close()
-	scanner := bufio.NewScanner(out)
-	for {
-		if tkn := scanner.Scan(); tkn {
-		} else {
-			break
-		}
+
+	_services, err := startServices(cli, config)
+	if err != nil {
+		return &runner.Runner{}, errors.Join(err, _services.remove())
+	}
+
+	hostConfig := &container.HostConfig{
+		AutoRemove: true,
+		Privileged: config.Privileged,
+	}
+	config.Resources.apply(hostConfig)
+	_services.hostConfig(hostConfig)
+	_workspace, err := ensureWorkspace(cli, config, &hostConfig.Mounts)
+	if err != nil {
+		return &runner.Runner{}, errors.Join(err, _services.remove())
 	}
 
 	resp, err := cli.ContainerCreate(context.Background(), &container.Config{
-		Image:        imageName,
+		Image:        config.Image,
 		AttachStderr: true,
 		AttachStdin:  true,
 		Tty:          false,
 		AttachStdout: true,
 		OpenStdin:    true,
-	},
-		&container.HostConfig{
-			AutoRemove: true,
-			Privileged: privileged,
-		}, nil, nil, "")
+		Labels:       config.Labels,
+	}, hostConfig, nil, nil, "")
 	if err != nil {
-		return &runner.Runner{}, err
+		return &runner.Runner{}, errors.Join(err, _services.remove())
 	}
 
 	conn, err := cli.ContainerAttach(context.Background(), resp.ID, types.ContainerAttachOptions{
@@ -47,22 +93,26 @@ func newRunner(cli *client.Client, imageName string, privileged bool) (*runner.R
 		Stderr: true,
 	})
 	if err != nil {
-		return &runner.Runner{}, err
+		return &runner.Runner{}, errors.Join(err, _services.remove())
 	}
 
 	err = cli.ContainerStart(context.Background(), resp.ID, types.ContainerStartOptions{})
 	if err != nil {
-		return &runner.Runner{}, err
+		return &runner.Runner{}, errors.Join(err, _services.remove())
 	}
 
 	_runner := runner.NewRunner(conn.Conn, conn.Conn)
+	_runner.Builder = imageBuilder{cli: cli, registry: config.Registry}
+	if _workspace != nil {
+		_runner.Workspace = _workspace
+	}
 	_runner.OnShutdown = func() error {
 		if err := conn.Conn.Close(); err != nil {
 			return err
 		}
-		return cli.ContainerRemove(context.Background(), resp.ID, types.ContainerRemoveOptions{
+		return errors.Join(cli.ContainerRemove(context.Background(), resp.ID, types.ContainerRemoveOptions{
 			Force: true,
-		})
+		}), _services.remove())
 	}
 
 	return _runner, nil
@@ -0,0 +1,140 @@
+package docker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// Service is a sidecar container reachable from the build container under
+// its name, the build starts once the health check passes.
+type Service struct {
+	Name        string
+	Image       string
+	Command     []string
+	Env         []string
+	HealthCheck string
+	Interval    time.Duration
+	Timeout     time.Duration
+}
+
+type services struct {
+	cli        *client.Client
+	network    string
+	containers []string
+}
+
+// startServices creates network for the build and starts the services on it,
+// nothing is created when there are no services.
+func startServices(cli *client.Client, config RunnerConfig) (*services, error) {
+	s := &services{cli: cli}
+	defs, labels := config.Services, config.Labels
+	if len(defs) == 0 {
+		return s, nil
+	}
+
+	for _, def := range defs {
+		if err := ensureImage(cli, def.Image, config.PullPolicy, config.Registry, config.Report); err != nil {
+			return s, err
+		}
+	}
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return s, err
+	}
+	name := "ccli-build-" + hex.EncodeToString(suffix)
+	if _, err := cli.NetworkCreate(context.Background(), name, types.NetworkCreate{
+		CheckDuplicate: true,
+		Labels:         labels,
+	}); err != nil {
+		return s, err
+	}
+	s.network = name
+
+	for _, def := range defs {
+		config := &container.Config{
+			Image:  def.Image,
+			Env:    def.Env,
+			Labels: withLabel(labels, LabelService, def.Name),
+		}
+		if len(def.Command) != 0 {
+			config.Cmd = def.Command
+		}
+		if def.HealthCheck != "" {
+			config.Healthcheck = &container.HealthConfig{
+				Test:     []string{"CMD-SHELL", def.HealthCheck},
+				Interval: def.Interval,
+			}
+		}
+		resp, err := cli.ContainerCreate(context.Background(), config, &container.HostConfig{},
+			&network.NetworkingConfig{
+				EndpointsConfig: map[string]*network.EndpointSettings{name: {Aliases: []string{def.Name}}},
+			}, nil, "")
+		if err != nil {
+			return s, err
+		}
+		s.containers = append(s.containers, resp.ID)
+		if err := cli.ContainerStart(context.Background(), resp.ID, types.ContainerStartOptions{}); err != nil {
+			return s, err
+		}
+	}
+
+	for i, def := range defs {
+		if err := s.waitReady(s.containers[i], def); err != nil {
+			return s, err
+		}
+		config.Report("service [" + def.Name + "] is ready")
+	}
+	return s, nil
+}
+
+func (s *services) waitReady(id string, def Service) error {
+	for deadline := time.Now().Add(def.Timeout); time.Now().Before(deadline); time.Sleep(def.Interval) {
+		info, err := s.cli.ContainerInspect(context.Background(), id)
+		if err != nil {
+			return err
+		}
+		if !info.State.Running {
+			return fmt.Errorf("service [%s] exited with code %d", def.Name, info.State.ExitCode)
+		}
+		if info.State.Health == nil || info.State.Health.Status == types.Healthy {
+			return nil
+		}
+	}
+	return fmt.Errorf("service [%s] did not become healthy in %s", def.Name, def.Timeout)
+}
+
+// hostConfig attaches the build container to the network of the services.
+func (s *services) hostConfig(hostConfig *container.HostConfig) {
+	if s.network != "" {
+		hostConfig.NetworkMode = container.NetworkMode(s.network)
+	}
+}
+
+func (s *services) remove() error {
+	errs := []error{}
+	for _, id := range s.containers {
+		errs = append(errs, s.cli.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{Force: true}))
+	}
+	if s.network != "" {
+		errs = append(errs, s.cli.NetworkRemove(context.Background(), s.network))
+	}
+	return errors.Join(errs...)
+}
+
+func withLabel(labels map[string]string, key, value string) map[string]string {
+	out := map[string]string{key: value}
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
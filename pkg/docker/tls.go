@@ -0,0 +1,117 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/docker/docker/client"
+)
+
+// TLS holds PEM encoded client certificate and key the daemon requires,
+// together with the CA its server certificate is verified against.
+type TLS struct {
+	CA   string `json:"ca"`
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+}
+
+func (t TLS) Empty() bool {
+	return t == TLS{}
+}
+
+func (t TLS) config() (*tls.Config, error) {
+	cert, err := tls.X509KeyPair([]byte(t.Cert), []byte(t.Key))
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(t.CA)) {
+		return nil, errors.New("invalid daemon CA certificate")
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      roots,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// SetTLS makes clients of the host verify the daemon and authenticate with
+// the certificate, the client connected so far is replaced.
+func SetTLS(host string, t TLS) error {
+	if _, err := t.config(); err != nil {
+		return err
+	}
+	if err := DeleteClient(host); err != nil {
+		return err
+	}
+	m := Get()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tls[host] = t
+	return nil
+}
+
+func DeleteTLS(host string) {
+	m := Get()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tls, host)
+}
+
+func newTLSClient(host string, t TLS) (*Client, error) {
+	config, err := t.config()
+	if err != nil {
+		return &Client{}, err
+	}
+	cli, err := client.NewClientWithOpts(
+		client.WithHTTPClient(&http.Client{Transport: &http.Transport{TLSClientConfig: config}}),
+		client.WithHost(host),
+		client.WithAPIVersionNegotiation(),
+	)
+	if err != nil {
+		return &Client{}, err
+	}
+	return &Client{
+		host:   host,
+		client: cli,
+	}, nil
+}
+
+// readCertificates reads client certificates generated by the daemon in the
+// container into its certificate directory.
+func readCertificates(cli *client.Client, containerID, dir string) (TLS, error) {
+	content, _, err := cli.CopyFromContainer(context.Background(), containerID, dir)
+	if err != nil {
+		return TLS{}, err
+	}
+	defer content.Close()
+
+	files := map[string]string{}
+	archive := tar.NewReader(content)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return TLS{}, err
+		}
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return TLS{}, err
+		}
+		files[path.Base(header.Name)] = string(data)
+	}
+
+	t := TLS{CA: files["ca.pem"], Cert: files["cert.pem"], Key: files["key.pem"]}
+	if t.CA == "" || t.Cert == "" || t.Key == "" {
+		return TLS{}, errors.New("daemon certificates are not generated yet")
+	}
+	return t, nil
+}
@@ -0,0 +1,67 @@
+package docker
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+// Workspace is named volume mounted at the work dir, it is created by the
+// first runner of the build and reused by the following ones.
+type Workspace struct {
+	Name   string
+	Path   string
+	Driver string
+	// Size in bytes is passed to the driver, honored only by some of them
+	// (e.g. local driver on xfs with pquota).
+	Size int64
+}
+
+var invalidVolumeChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+func WorkspaceName(buildID string) string {
+	return "ccli-workspace-" + invalidVolumeChars.ReplaceAllString(buildID, "-")
+}
+
+type workspace struct {
+	cli  *client.Client
+	name string
+	path string
+}
+
+// ensureWorkspace creates the volume unless it exists and mounts it into the
+// build container, nothing is done when the runner has no workspace.
+func ensureWorkspace(cli *client.Client, config RunnerConfig, mounts *[]mount.Mount) (*workspace, error) {
+	ws := config.Workspace
+	if ws.Name == "" {
+		return nil, nil
+	}
+	if _, err := cli.VolumeInspect(context.Background(), ws.Name); err == nil {
+		config.Report("reusing workspace volume [" + ws.Name + "]")
+	} else if client.IsErrNotFound(err) {
+		options := volume.CreateOptions{Name: ws.Name, Driver: ws.Driver, Labels: config.Labels}
+		if ws.Size != 0 {
+			options.DriverOpts = map[string]string{"size": strconv.FormatInt(ws.Size, 10)}
+		}
+		if _, err := cli.VolumeCreate(context.Background(), options); err != nil {
+			return nil, err
+		}
+		config.Report("created workspace volume [" + ws.Name + "]")
+	} else {
+		return nil, err
+	}
+	*mounts = append(*mounts, mount.Mount{Type: mount.TypeVolume, Source: ws.Name, Target: ws.Path})
+	return &workspace{cli: cli, name: ws.Name, path: ws.Path}, nil
+}
+
+func (w *workspace) Path() string {
+	return w.path
+}
+
+func (w *workspace) Remove() error {
+	return w.cli.VolumeRemove(context.Background(), w.name, true)
+}
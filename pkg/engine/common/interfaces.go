@@ -7,7 +7,10 @@ import (
 
 type IBinder interface {
 	Bind() error
-	Unbind(workerName string) error
+	Unbind(build model.Build) error
+	// Reconcile removes containers or pods of builds for which keep returns
+	// false and repairs bookkeeping of the workers.
+	Reconcile(keep func(buildID, instance string) bool) error
 
 	SetOnBind(callback func(model.QueueContext, *runner.Runner))
 }
@@ -0,0 +1,202 @@
+package common
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/model"
+	"gorm.io/gorm"
+)
+
+// Planner decides in which order queued builds are bound. Builds with higher
+// priority go first, projects share workers according to their weights and
+// neither project nor pipeline exceeds its limit of concurrent builds. Only
+// one build of each concurrency group runs at once.
+type Planner struct {
+	queues    map[string][]model.QueueElem
+	projects  map[string]model.Project
+	running   map[string]int
+	pipelines map[string]int
+	groups    map[string]bool
+}
+
+func NewPlanner(queue []model.QueueElem, projects []model.Project, running []model.Build) *Planner {
+	p := &Planner{
+		queues:    map[string][]model.QueueElem{},
+		projects:  map[string]model.Project{},
+		running:   map[string]int{},
+		pipelines: map[string]int{},
+		groups:    map[string]bool{},
+	}
+	for _, elem := range queue {
+		name := elem.Context.Build.ProjectName
+		p.queues[name] = append(p.queues[name], elem)
+	}
+	for name := range p.queues {
+		sort.SliceStable(p.queues[name], func(i, j int) bool {
+			return before(p.queues[name][i], p.queues[name][j])
+		})
+	}
+	for _, project := range projects {
+		p.projects[project.Name] = project
+	}
+	for _, build := range running {
+		p.running[build.ProjectName]++
+		p.pipelines[build.ProjectName+"/"+build.PipelineName]++
+		if build.ConcurrencyGroup != "" {
+			p.groups[build.ConcurrencyGroup] = true
+		}
+	}
+	return p
+}
+
+// LoadPlanner creates the planner for the queue with limits and builds
+// currently running read from the database.
+func LoadPlanner(tx *gorm.DB, queue []model.QueueElem) (*Planner, error) {
+	var projects []model.Project
+	if err := tx.Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	var running []model.Build
+	if err := tx.Select("project_name", "pipeline_name", "concurrency_group").Where(&model.Build{Status: model.BuildRunning}).Find(&running).Error; err != nil {
+		return nil, err
+	}
+	return NewPlanner(queue, projects, running), nil
+}
+
+// Next removes and returns the build which should be bound now, if any may be.
+func (p *Planner) Next() (model.QueueElem, bool) {
+	names := make([]string, 0, len(p.queues))
+	for name := range p.queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best, bestIdx, bestProject := model.QueueElem{}, -1, ""
+	for _, name := range names {
+		if p.projectFull(name) {
+			continue
+		}
+		for idx, elem := range p.queues[name] {
+			if p.pipelineFull(elem) || p.groups[elem.Context.Build.ConcurrencyGroup] {
+				continue
+			}
+			if bestIdx == -1 || p.better(elem, best) {
+				best, bestIdx, bestProject = elem, idx, name
+			}
+			break
+		}
+	}
+	if bestIdx == -1 {
+		return model.QueueElem{}, false
+	}
+
+	queue := p.queues[bestProject]
+	p.queues[bestProject] = append(queue[:bestIdx:bestIdx], queue[bestIdx+1:]...)
+	if len(p.queues[bestProject]) == 0 {
+		delete(p.queues, bestProject)
+	}
+	return best, true
+}
+
+// Bound counts the build returned by Next as running.
+func (p *Planner) Bound(elem model.QueueElem) {
+	p.running[elem.Context.Build.ProjectName]++
+	p.pipelines[pipelineKey(elem)]++
+	if group := elem.Context.Build.ConcurrencyGroup; group != "" {
+		p.groups[group] = true
+	}
+}
+
+// Blocked returns why the builds left in the planner cannot be bound, if
+// they are held back by a limit or concurrency group.
+func (p *Planner) Blocked() map[string]string {
+	reasons := map[string]string{}
+	for name, queue := range p.queues {
+		for _, elem := range queue {
+			switch {
+			case p.projectFull(name):
+				reasons[elem.ID] = model.WaitProjectLimit
+			case p.pipelineFull(elem):
+				reasons[elem.ID] = model.WaitPipelineLimit
+			case p.groups[elem.Context.Build.ConcurrencyGroup]:
+				reasons[elem.ID] = model.WaitConcurrencyGroup
+			}
+		}
+	}
+	return reasons
+}
+
+// RecordWaits stores the outcome of the bind attempt on builds left in the
+// queue, falling back to the given reason for builds not attempted at all.
+func (p *Planner) RecordWaits(tx *gorm.DB, reasons map[string]string, fallback string) error {
+	for id, reason := range p.Blocked() {
+		reasons[id] = reason
+	}
+	for _, queue := range p.queues {
+		for _, elem := range queue {
+			if _, ok := reasons[elem.ID]; !ok {
+				reasons[elem.ID] = fallback
+			}
+		}
+	}
+
+	now := sql.NullTime{Time: time.Now(), Valid: true}
+	for id, reason := range reasons {
+		if err := tx.Model(&model.QueueElem{ID: id}).UpdateColumns(map[string]any{
+			"wait_reason":     reason,
+			"last_attempt_at": now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Planner) projectFull(name string) bool {
+	limit := p.projects[name].MaxConcurrent
+	return limit > 0 && p.running[name] >= limit
+}
+
+func (p *Planner) pipelineFull(elem model.QueueElem) bool {
+	limit := elem.Context.Config.MaxConcurrent
+	return limit > 0 && p.pipelines[pipelineKey(elem)] >= limit
+}
+
+// better compares heads of two project queues, preferring higher priority,
+// then the project using the smaller part of its share, then the older build.
+func (p *Planner) better(a, b model.QueueElem) bool {
+	if a.Context.Build.Priority != b.Context.Build.Priority {
+		return a.Context.Build.Priority > b.Context.Build.Priority
+	}
+	projectA, projectB := a.Context.Build.ProjectName, b.Context.Build.ProjectName
+	usageA := p.running[projectA] * p.weight(projectB)
+	usageB := p.running[projectB] * p.weight(projectA)
+	if usageA != usageB {
+		return usageA < usageB
+	}
+	return before(a, b)
+}
+
+func (p *Planner) weight(name string) int {
+	if project, ok := p.projects[name]; ok && project.Weight > 0 {
+		return project.Weight
+	}
+	return 1
+}
+
+func before(a, b model.QueueElem) bool {
+	switch {
+	case a.Context.Build.Priority != b.Context.Build.Priority:
+		return a.Context.Build.Priority > b.Context.Build.Priority
+	case !a.CreatedAt.Equal(b.CreatedAt):
+		return a.CreatedAt.Before(b.CreatedAt)
+	default:
+		return a.ID < b.ID
+	}
+}
+
+func pipelineKey(elem model.QueueElem) string {
+	return elem.Context.Build.ProjectName + "/" + elem.Context.Build.PipelineName
+}
@@ -0,0 +1,223 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/model"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type queued struct {
+	id, project, pipeline string
+	priority, age         int
+	group                 string
+	maxConcurrent         int
+}
+
+func (q queued) elem() model.QueueElem {
+	return model.QueueElem{
+		ID: q.id,
+		Context: model.QueueContext{
+			Build: model.Build{
+				ProjectName:      q.project,
+				PipelineName:     q.pipeline,
+				Priority:         q.priority,
+				ConcurrencyGroup: q.group,
+			},
+			Config: model.PipelineConfig{MaxConcurrent: q.maxConcurrent},
+		},
+		CreatedAt: epoch.Add(-time.Duration(q.age) * time.Minute),
+	}
+}
+
+func running(project, pipeline, group string, n int) []model.Build {
+	builds := []model.Build{}
+	for i := 0; i < n; i++ {
+		builds = append(builds, model.Build{ProjectName: project, PipelineName: pipeline, ConcurrencyGroup: group})
+	}
+	return builds
+}
+
+func TestPlanner(t *testing.T) {
+	tests := []struct {
+		name     string
+		queue    []queued
+		projects []model.Project
+		running  []model.Build
+		want     []string
+		blocked  map[string]string
+	}{
+		{
+			name: "oldest first",
+			queue: []queued{
+				{id: "a", project: "p", pipeline: "x", age: 1},
+				{id: "b", project: "p", pipeline: "x", age: 3},
+				{id: "c", project: "p", pipeline: "x", age: 2},
+			},
+			want: []string{"b", "c", "a"},
+		},
+		{
+			name: "priority before age",
+			queue: []queued{
+				{id: "old", project: "p", pipeline: "x", age: 9},
+				{id: "urgent", project: "p", pipeline: "x", priority: 5, age: 1},
+				{id: "other", project: "q", pipeline: "y", priority: 3, age: 5},
+			},
+			want: []string{"urgent", "other", "old"},
+		},
+		{
+			name: "same age ordered by id",
+			queue: []queued{
+				{id: "b", project: "p", pipeline: "x"},
+				{id: "a", project: "p", pipeline: "x"},
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "equal weights alternate",
+			queue: []queued{
+				{id: "a1", project: "a", pipeline: "x", age: 9},
+				{id: "a2", project: "a", pipeline: "x", age: 8},
+				{id: "a3", project: "a", pipeline: "x", age: 7},
+				{id: "b1", project: "b", pipeline: "y", age: 2},
+				{id: "b2", project: "b", pipeline: "y", age: 1},
+			},
+			want: []string{"a1", "b1", "a2", "b2", "a3"},
+		},
+		{
+			name: "running builds count to share",
+			queue: []queued{
+				{id: "a1", project: "a", pipeline: "x", age: 9},
+				{id: "b1", project: "b", pipeline: "y", age: 1},
+			},
+			running: running("a", "x", "", 2),
+			want:    []string{"b1", "a1"},
+		},
+		{
+			name: "weighted share",
+			queue: []queued{
+				{id: "a1", project: "a", pipeline: "x", age: 20},
+				{id: "a2", project: "a", pipeline: "x", age: 19},
+				{id: "a3", project: "a", pipeline: "x", age: 18},
+				{id: "a4", project: "a", pipeline: "x", age: 17},
+				{id: "a5", project: "a", pipeline: "x", age: 16},
+				{id: "a6", project: "a", pipeline: "x", age: 15},
+				{id: "b1", project: "b", pipeline: "y", age: 2},
+				{id: "b2", project: "b", pipeline: "y", age: 1},
+			},
+			projects: []model.Project{{Name: "a", Weight: 3}, {Name: "b", Weight: 1}},
+			want:     []string{"a1", "b1", "a2", "a3", "a4", "b2", "a5", "a6"},
+		},
+		{
+			name: "priority wins over share",
+			queue: []queued{
+				{id: "a1", project: "a", pipeline: "x", priority: 1, age: 1},
+				{id: "b1", project: "b", pipeline: "y", age: 9},
+			},
+			running: running("a", "x", "", 5),
+			want:    []string{"a1", "b1"},
+		},
+		{
+			name: "project limit",
+			queue: []queued{
+				{id: "a1", project: "a", pipeline: "x", age: 9},
+				{id: "a2", project: "a", pipeline: "x", age: 8},
+				{id: "b1", project: "b", pipeline: "y", age: 1},
+			},
+			projects: []model.Project{{Name: "a", MaxConcurrent: 2}},
+			running:  running("a", "x", "", 1),
+			want:     []string{"b1", "a1"},
+			blocked:  map[string]string{"a2": model.WaitProjectLimit},
+		},
+		{
+			name: "pipeline limit skips to next pipeline",
+			queue: []queued{
+				{id: "x1", project: "a", pipeline: "x", age: 9, maxConcurrent: 1},
+				{id: "x2", project: "a", pipeline: "x", age: 8, maxConcurrent: 1},
+				{id: "y1", project: "a", pipeline: "y", age: 1},
+			},
+			want:    []string{"x1", "y1"},
+			blocked: map[string]string{"x2": model.WaitPipelineLimit},
+		},
+		{
+			name: "pipeline limit with running builds",
+			queue: []queued{
+				{id: "x1", project: "a", pipeline: "x", age: 9, maxConcurrent: 2},
+			},
+			running: running("a", "x", "", 2),
+			want:    []string{},
+			blocked: map[string]string{"x1": model.WaitPipelineLimit},
+		},
+		{
+			name: "one build per concurrency group",
+			queue: []queued{
+				{id: "g1", project: "a", pipeline: "x", age: 9, group: "deploy"},
+				{id: "g2", project: "b", pipeline: "y", age: 8, group: "deploy"},
+				{id: "h1", project: "b", pipeline: "y", age: 1, group: "other"},
+			},
+			want:    []string{"g1", "h1"},
+			blocked: map[string]string{"g2": model.WaitConcurrencyGroup},
+		},
+		{
+			name: "concurrency group taken by running build",
+			queue: []queued{
+				{id: "g1", project: "a", pipeline: "x", age: 9, group: "deploy"},
+			},
+			running: running("a", "x", "deploy", 1),
+			want:    []string{},
+			blocked: map[string]string{"g1": model.WaitConcurrencyGroup},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := []model.QueueElem{}
+			for _, q := range tt.queue {
+				queue = append(queue, q.elem())
+			}
+			p := NewPlanner(queue, tt.projects, tt.running)
+
+			got := []string{}
+			for {
+				elem, ok := p.Next()
+				if !ok {
+					break
+				}
+				p.Bound(elem)
+				got = append(got, elem.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bind order = %v, want %v", got, tt.want)
+			}
+
+			blocked := tt.blocked
+			if blocked == nil {
+				blocked = map[string]string{}
+			}
+			if reasons := p.Blocked(); !reflect.DeepEqual(reasons, blocked) {
+				t.Errorf("Blocked() = %v, want %v", reasons, blocked)
+			}
+		})
+	}
+}
+
+func TestPlannerNextWithoutBound(t *testing.T) {
+	queue := []model.QueueElem{
+		queued{id: "x1", project: "a", pipeline: "x", age: 2, maxConcurrent: 1}.elem(),
+		queued{id: "x2", project: "a", pipeline: "x", age: 1, maxConcurrent: 1}.elem(),
+	}
+	p := NewPlanner(queue, nil, nil)
+
+	// Build which failed to bind is not counted, so the next one may go.
+	for _, want := range []string{"x1", "x2"} {
+		elem, ok := p.Next()
+		if !ok || elem.ID != want {
+			t.Fatalf("Next() = %s, %v, want %s", elem.ID, ok, want)
+		}
+	}
+	if _, ok := p.Next(); ok {
+		t.Error("Next() on empty planner returned build")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gg-mike/ccli/pkg/runner"
 )
 
 type envInstance struct {
@@ -15,8 +16,8 @@ type envInstance struct {
 	path  string
 }
 
-func createEnvSteps(ctx *model.QueueContext) error {
-	workdirSteps, workdirCleanup := createWorkdirStep(ctx)
+func createEnvSteps(ctx *model.QueueContext, workspace runner.Workspace) error {
+	workdirSteps, workdirCleanup := createWorkdirStep(ctx, workspace)
 	secretsSteps, secretsCleanup, err := createSecretsStep(ctx)
 	if err != nil {
 		return err
@@ -37,7 +38,14 @@ func createEnvSteps(ctx *model.QueueContext) error {
 	return nil
 }
 
-func createWorkdirStep(ctx *model.QueueContext) (model.PipelineConfigStep, []string) {
+func createWorkdirStep(ctx *model.QueueContext, workspace runner.Workspace) (model.PipelineConfigStep, []string) {
+	if workspace != nil {
+		// The volume is removed with its content once the build ends.
+		return model.PipelineConfigStep{
+			Name:     "Work dir setup",
+			Commands: []string{"cd " + workspace.Path()},
+		}, []string{}
+	}
 	workdir := strings.ReplaceAll(ctx.Build.ID(), "/", "_")
 	return model.PipelineConfigStep{
 		Name:     "Work dir setup",
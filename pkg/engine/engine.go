@@ -16,6 +16,7 @@ const (
 	EventFinished
 	EventAddToQueue
 	EventChangeInWorkers
+	EventReconcile
 	EventShutdown
 )
 
@@ -28,6 +29,13 @@ const (
 	EventFailed
 )
 
+type Config struct {
+	// Instance identifies this server on bound builds and on containers and
+	// pods created for them.
+	Instance          string
+	ReconcileInterval time.Duration
+}
+
 type Engine struct {
 	newBuild        chan string
 	finishedBuild   chan string
@@ -36,11 +44,12 @@ type Engine struct {
 	shutdown        chan any
 	done            chan any
 
+	config Config
 	logger log.Logger
 	binder common.IBinder
 }
 
-func NewEngine(logger log.Logger, binder common.IBinder) *Engine {
+func NewEngine(logger log.Logger, binder common.IBinder, config Config) *Engine {
 	return &Engine{
 		newBuild:        make(chan string),
 		finishedBuild:   make(chan string),
@@ -49,6 +58,7 @@ func NewEngine(logger log.Logger, binder common.IBinder) *Engine {
 		shutdown:        make(chan any),
 		done:            make(chan any),
 
+		config: config,
 		logger: logger.NewComponentLogger("engine"),
 		binder: binder,
 	}
@@ -60,10 +70,20 @@ func (e *Engine) Run() {
 	e.logger.Debug().Msg("binding any builds scheduled in previous run")
 
 	e.binder.SetOnBind(e.execute)
+	if err := e.reconcile(true); err != nil {
+		e.logger.Error().Err(err).Msg("reconciliation ended with error")
+	}
 	if err := e.binder.Bind(); err != nil {
 		e.logger.Error().Err(err).Msg("bind ended with error")
 	}
 
+	var reconcileTick <-chan time.Time
+	if e.config.ReconcileInterval > 0 {
+		ticker := time.NewTicker(e.config.ReconcileInterval)
+		defer ticker.Stop()
+		reconcileTick = ticker.C
+	}
+
 	run := true
 
 	for run {
@@ -85,6 +105,13 @@ func (e *Engine) Run() {
 		case ctx := <-e.addToQueue:
 			e.logger.Debug().Str("event", EventAddToQueue.String()).Str("status", EventProcessed.String()).Str("build_id", ctx.Build.ID()).Send()
 
+			// Build might have been superseded or canceled while its context was created.
+			build := model.BuildFromID(ctx.Build.ID())
+			if err := db.Get().First(&build).Error; err != nil || build.Status == model.BuildCanceled {
+				e.logger.Debug().Str("event", EventAddToQueue.String()).Str("status", EventComplete.String()).Str("build_id", ctx.Build.ID()).Msg("build canceled before queueing")
+				continue
+			}
+
 			ctx.Build.Steps = append(ctx.Build.Steps, model.BuildStep{
 				Name:         "Worker binding",
 				BuildNumber:  ctx.Build.Number,
@@ -112,6 +139,16 @@ func (e *Engine) Run() {
 			} else {
 				e.logger.Debug().Str("event", EventChangeInWorkers.String()).Str("status", EventComplete.String()).Send()
 			}
+		case <-reconcileTick:
+			e.logger.Debug().Str("event", EventReconcile.String()).Str("status", EventProcessed.String()).Send()
+
+			if err := e.reconcile(false); err != nil {
+				e.logger.Error().Str("event", EventReconcile.String()).Str("status", EventFailed.String()).Err(err).Send()
+			} else if err := e.binder.Bind(); err != nil {
+				e.logger.Error().Str("event", EventReconcile.String()).Str("status", EventFailed.String()).Err(err).Send()
+			} else {
+				e.logger.Debug().Str("event", EventReconcile.String()).Str("status", EventComplete.String()).Send()
+			}
 		case <-e.shutdown:
 			e.logger.Debug().Str("event", EventShutdown.String()).Str("status", EventProcessed.String()).Send()
 
@@ -161,6 +198,8 @@ func (e EngineEvent) String() string {
 		return "add-to-queue"
 	case EventChangeInWorkers:
 		return "change-in-workers"
+	case EventReconcile:
+		return "reconcile"
 	case EventShutdown:
 		return "shutdown"
 	default:
@@ -14,27 +14,30 @@ func (e *Engine) execute(ctx model.QueueContext, _runner *runner.Runner) {
 	build := model.BuildFromID(ctx.Build.ID())
 	e.logger.Debug().Str("build_id", build.ID()).Str("step", "execute").Msg("build execution started")
 
-	var err error
-	if err = e.run(&ctx, _runner); err != nil && err != ErrBuildCancelled {
-		go e.Finished(build.ID())
-		e.logger.Warn().Str("build_id", build.ID()).Str("step", "execute").Err(err).Msg("build execution ended with error")
-		if err := db.Get().Model(&build).UpdateColumn("status", model.BuildFailed).Error; err != nil {
-			e.logger.Error().Str("build_id", build.ID()).Str("step", "execute").Err(err).Msg("could not update build")
-			return
-		}
+	err := e.run(&ctx, _runner)
+	if err == ErrBuildCancelled {
+		// Cancel already set the status and unbinds the build.
+		e.logger.Debug().Str("build_id", build.ID()).Str("step", "execute").Msg("build execution cancelled")
 		return
 	}
 
-	go e.Finished(build.ID())
-
-	e.logger.Debug().Str("build_id", build.ID()).Str("step", "execute").Str("status", ctx.Build.Status).Msg("build execution ended")
-
-	if err == ErrBuildCancelled {
-		return
+	status := model.BuildSuccessful
+	if err != nil {
+		e.logger.Warn().Str("build_id", build.ID()).Str("step", "execute").Err(err).Msg("build execution ended with error")
+		status = model.BuildFailed
 	}
 
-	if err := db.Get().Model(&build).UpdateColumn("status", model.BuildSuccessful).Error; err != nil {
-		e.logger.Error().Str("build_id", build.ID()).Str("step", "execute").Err(err).Msg("could not update build")
+	// Only the build still running is finished here, one cancelled meanwhile
+	// was already unbound by Cancel.
+	result := db.Get().Model(&build).Where("status = ?", model.BuildRunning).UpdateColumn("status", status)
+	if result.Error != nil {
+		e.logger.Error().Str("build_id", build.ID()).Str("step", "execute").Err(result.Error).Msg("could not update build")
+	} else if result.RowsAffected == 0 {
+		e.logger.Debug().Str("build_id", build.ID()).Str("step", "execute").Msg("build cancelled before it ended")
 		return
 	}
+
+	go e.Finished(build.ID())
+
+	e.logger.Debug().Str("build_id", build.ID()).Str("step", "execute").Str("status", status).Msg("build execution ended")
 }
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/local"
+	"github.com/gg-mike/ccli/pkg/model"
+	"gorm.io/gorm"
+)
+
+func TestSupersededRunningBuildIsCanceled(t *testing.T) {
+	requireDB(t)
+
+	pipeline := createPipeline(t, model.PipelineConfig{
+		System:      "linux",
+		Concurrency: model.ConcurrencyConfig{Group: "${project}-deploy", CancelInProgress: true},
+		Steps: []model.PipelineConfigStep{
+			{Name: "Deploy", Commands: []string{"sleep 1"}},
+			{Name: "Verify", Commands: []string{"true"}},
+		},
+	})
+	ctx := startBuild(t, pipeline, "local")
+	_runner, err := local.NewRunner(local.Options{Home: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan any)
+	go func() {
+		testEngine.execute(ctx, _runner)
+		close(done)
+	}()
+
+	newer := model.Build{ProjectName: pipeline.ProjectName, PipelineName: pipeline.Name}
+	if err := db.Get().Create(&newer).Error; err != nil {
+		t.Fatal(err)
+	}
+	newer.AfterCommit()
+	<-done
+
+	build := model.BuildFromID(ctx.Build.ID())
+	if err := db.Get().First(&build).Error; err != nil {
+		t.Fatal(err)
+	}
+	if build.Status != model.BuildCanceled {
+		t.Errorf("superseded build status = %s, want %s", build.Status, model.BuildCanceled)
+	}
+	if build.StatusReason != "superseded by build "+newer.ID() {
+		t.Errorf("superseded build reason = %q", build.StatusReason)
+	}
+
+	waitFor(t, "build to be unbound", func() bool { return testBinder.Unbound(build.ID()) > 0 })
+	if n := testBinder.Unbound(build.ID()); n != 1 {
+		t.Errorf("superseded build unbound %d times, want 1", n)
+	}
+
+	var steps []model.BuildStep
+	if err := db.Get().Where(&model.BuildStep{BuildNumber: build.Number, PipelineName: build.PipelineName, ProjectName: build.ProjectName}).Find(&steps).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range steps {
+		if step.Name == "Verify" {
+			t.Error("step after cancellation was run")
+		}
+	}
+}
+
+func TestRolledBackSupersedeKeepsBuildRunning(t *testing.T) {
+	requireDB(t)
+
+	pipeline := createPipeline(t, model.PipelineConfig{
+		System:      "linux",
+		Concurrency: model.ConcurrencyConfig{Group: "${project}-deploy", CancelInProgress: true},
+	})
+	ctx := startBuild(t, pipeline, "local")
+
+	rollback := errors.New("rollback")
+	newer := model.Build{ProjectName: pipeline.ProjectName, PipelineName: pipeline.Name}
+	err := db.Get().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newer).Error; err != nil {
+			return err
+		}
+		return rollback
+	})
+	if err != rollback {
+		t.Fatalf("transaction error = %v", err)
+	}
+
+	build := model.BuildFromID(ctx.Build.ID())
+	if err := db.Get().First(&build).Error; err != nil {
+		t.Fatal(err)
+	}
+	if build.Status != model.BuildRunning {
+		t.Errorf("build status = %s after rolled back supersede, want %s", build.Status, model.BuildRunning)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := testBinder.Unbound(build.ID()); n != 0 {
+		t.Errorf("build unbound %d times after rolled back supersede", n)
+	}
+}
@@ -16,7 +16,7 @@ func (e *Engine) finished(buildID string) error {
 		return nil
 	}
 
-	if err := e.binder.Unbind(build.WorkerName.String); err != nil {
+	if err := e.binder.Unbind(build); err != nil {
 		return err
 	}
 
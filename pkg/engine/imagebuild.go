@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gg-mike/ccli/pkg/runner"
+)
+
+var ErrImageBuildUnsupported = errors.New("image_build steps require Docker or Kubernetes worker")
+
+// buildImage archives the build context inside the build environment, hands
+// it to the worker's image builder and records the image on the build.
+func buildImage(ctx *model.QueueContext, _runner *runner.Runner, step model.PipelineConfigStep, buildStep *model.BuildStep) error {
+	if _runner.Builder == nil {
+		return ErrImageBuildUnsupported
+	}
+	config := *step.ImageBuild
+
+	archive, err := _runner.Capture("tar -C " + runner.Quote(config.ContextDir()) + " -cf - .")
+	if err != nil {
+		return fmt.Errorf("archiving build context: %w", err)
+	}
+
+	tags := config.References(ctx.Build, ctx.Branch)
+	buildStep.AppendLog(model.BuildLog{Command: "[image_build] " + strings.Join(tags, ", ")})
+	digest, err := _runner.Builder.BuildImage(runner.ImageSpec{
+		Dockerfile: config.DockerfilePath(),
+		Tags:       tags,
+		BuildArgs:  config.BuildArgs,
+		Labels:     model.ImageLabels(ctx.Build, ctx.Repo, ctx.Branch),
+		Push:       config.Push,
+	}, bytes.NewReader(archive), onOut(buildStep))
+	if err != nil {
+		return err
+	}
+	_runner.OnOut(fmt.Sprintf("built image %s", digest))
+
+	ctx.Build.Images = append(ctx.Build.Images, model.BuiltImage{
+		Step:   step.Name,
+		Tags:   tags,
+		Digest: digest,
+		Pushed: config.Push,
+	})
+	return db.Get().Select("images").UpdateColumns(&ctx.Build).Error
+}
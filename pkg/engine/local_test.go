@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/executor"
+	"github.com/gg-mike/ccli/pkg/model"
+)
+
+func TestBuildOnLocalWorker(t *testing.T) {
+	requireDB(t)
+
+	pipeline := createPipeline(t, model.PipelineConfig{
+		System: "linux",
+		Steps: []model.PipelineConfigStep{
+			{Name: "Build", Commands: []string{"echo built > artifact"}},
+			{Name: "Check", Commands: []string{"cat artifact"}},
+		},
+	})
+	ctx := startBuild(t, pipeline, "local")
+	worker := model.Worker{Name: "local", Type: model.WorkerLocal, IsStatic: true, Local: model.LocalConfig{Home: t.TempDir()}}
+
+	_executor, err := executor.Get(worker.ExecutorType())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_runner, err := _executor.Prepare(executor.Job{Context: &ctx, Worker: worker, Report: func(string) {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testEngine.execute(ctx, _runner)
+
+	build := model.BuildFromID(ctx.Build.ID())
+	if err := db.Get().First(&build).Error; err != nil {
+		t.Fatal(err)
+	}
+	if build.Status != model.BuildSuccessful {
+		t.Errorf("build status = %s, want %s", build.Status, model.BuildSuccessful)
+	}
+
+	waitFor(t, "build to be unbound", func() bool { return testBinder.Unbound(build.ID()) > 0 })
+	if n := testBinder.Unbound(build.ID()); n != 1 {
+		t.Errorf("build unbound %d times, want 1", n)
+	}
+
+	step := model.BuildStep{Name: "Check", BuildNumber: build.Number, PipelineName: build.PipelineName, ProjectName: build.ProjectName}
+	if err := db.Get().Where(&step).First(&step).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(step.Logs) != 1 || step.Logs[0].Output != "built" {
+		t.Errorf("Check step logs = %+v, want output of the Build step", step.Logs)
+	}
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/log"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gg-mike/ccli/pkg/runner"
+	"github.com/gg-mike/ccli/pkg/scheduler"
+	"gorm.io/gorm"
+)
+
+// Tests of the engine run against PostgreSQL given by CCLI_TEST_DB_URL and
+// are skipped without it.
+var (
+	testEngine *Engine
+	testBinder = &recordingBinder{unbound: map[string]int{}}
+)
+
+func TestMain(m *testing.M) {
+	if url := os.Getenv("CCLI_TEST_DB_URL"); url != "" {
+		logger := log.NewLogger("ccli", "test", "warn", "")
+		if err := db.Init(url, log.Gorm(logger)); err != nil {
+			fmt.Fprintln(os.Stderr, "could not connect to the test db:", err)
+			os.Exit(1)
+		}
+		if err := db.Get().AutoMigrate(
+			&model.Worker{}, &model.Project{}, &model.Pipeline{}, &model.Build{},
+			&model.BuildStep{}, &model.Secret{}, &model.Variable{}, &model.QueueElem{},
+		); err != nil {
+			fmt.Fprintln(os.Stderr, "could not migrate the test db:", err)
+			os.Exit(1)
+		}
+		testEngine = NewEngine(logger, testBinder, Config{Instance: "test"})
+		scheduler.Init(testScheduler{testEngine})
+	}
+	os.Exit(m.Run())
+}
+
+func requireDB(t *testing.T) {
+	t.Helper()
+	if testEngine == nil {
+		t.Skip("CCLI_TEST_DB_URL is not set")
+	}
+}
+
+// testScheduler finishes builds right away instead of going through the
+// engine loop, builds are never scheduled.
+type testScheduler struct {
+	engine *Engine
+}
+
+func (s testScheduler) Schedule(string) {}
+
+func (s testScheduler) Finished(buildID string) {
+	s.engine.finished(buildID)
+}
+
+func (s testScheduler) ChangeInWorkers() {}
+
+// recordingBinder counts how many times each build was unbound.
+type recordingBinder struct {
+	mu      sync.Mutex
+	unbound map[string]int
+}
+
+func (b *recordingBinder) Bind() error { return nil }
+
+func (b *recordingBinder) Unbind(build model.Build) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.unbound[build.ID()]++
+	return nil
+}
+
+func (b *recordingBinder) Unbound(buildID string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.unbound[buildID]
+}
+
+func (b *recordingBinder) Reconcile(func(buildID, instance string) bool) error { return nil }
+
+func (b *recordingBinder) SetOnBind(func(model.QueueContext, *runner.Runner)) {}
+
+// createPipeline stores the project with a single pipeline, both removed
+// when the test ends.
+func createPipeline(t *testing.T, config model.PipelineConfig) model.Pipeline {
+	t.Helper()
+	project := model.Project{Name: fmt.Sprintf("test-%d", time.Now().UnixNano()), Repo: "https://example.com/repo.git"}
+	if err := db.Get().Create(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Get().Session(&gorm.Session{SkipHooks: true}).Delete(&project)
+	})
+	pipeline := model.Pipeline{Name: "main", ProjectName: project.Name, Branch: "main", Config: config}
+	if err := db.Get().Create(&pipeline).Error; err != nil {
+		t.Fatal(err)
+	}
+	return pipeline
+}
+
+// startBuild stores the build as if the binder bound it to the worker.
+func startBuild(t *testing.T, pipeline model.Pipeline, workerName string) model.QueueContext {
+	t.Helper()
+	build := model.Build{
+		Number:           1,
+		ProjectName:      pipeline.ProjectName,
+		PipelineName:     pipeline.Name,
+		Status:           model.BuildRunning,
+		ConcurrencyGroup: pipeline.Config.Concurrency.GroupFor(pipeline.ProjectName, pipeline.Name, pipeline.Branch),
+		Instance:         "test",
+	}
+	build.WorkerName.String, build.WorkerName.Valid = workerName, true
+	if err := db.Get().Session(&gorm.Session{SkipHooks: true}).Create(&build).Error; err != nil {
+		t.Fatal(err)
+	}
+	return model.QueueContext{Build: build, Branch: pipeline.Branch, Config: pipeline.Config}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
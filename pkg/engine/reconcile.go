@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/model"
+	"gorm.io/gorm"
+)
+
+// reconcile fails builds left running by other server instances, resumes
+// scheduled builds which never reached the queue and lets the binder remove
+// resources nobody owns. On startup every scheduled build without queue
+// element is resumed, later only those older than the reconcile interval.
+func (e *Engine) reconcile(startup bool) error {
+	// Nothing runs in a freshly started engine, even builds of this instance
+	// are left from the previous run.
+	query := db.Get().Where("status = ?", model.BuildRunning)
+	if !startup {
+		query = query.Where("instance <> ?", e.config.Instance)
+	}
+	var orphaned []model.Build
+	if err := query.Find(&orphaned).Error; err != nil {
+		return err
+	}
+	for _, build := range orphaned {
+		e.logger.Warn().Str("build_id", build.ID()).Str("instance", build.Instance).Msg("failing orphaned build")
+		if err := db.Get().Model(&build).UpdateColumns(map[string]any{
+			"status":        model.BuildFailed,
+			"status_reason": "orphaned by server instance [" + build.Instance + "]",
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	cutoff := time.Now().Add(-e.config.ReconcileInterval)
+	if startup {
+		cutoff = time.Now()
+	}
+	var scheduled []model.Build
+	if err := db.Get().Where("status = ? AND created_at < ?", model.BuildScheduled, cutoff).Find(&scheduled).Error; err != nil {
+		return err
+	}
+	for _, build := range scheduled {
+		err := db.Get().First(&model.QueueElem{}, &model.QueueElem{ID: build.ID()}).Error
+		if err == nil {
+			continue
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		e.logger.Info().Str("build_id", build.ID()).Msg("resuming scheduled build missing from queue")
+		e.schedule(build.ID())
+	}
+
+	return e.binder.Reconcile(e.owned)
+}
+
+// owned tells whether resources labeled with the build and instance are still
+// in use. Scheduled builds may be just getting their container or pod.
+func (e *Engine) owned(buildID, instance string) bool {
+	if strings.Count(buildID, "/") != 2 {
+		return false
+	}
+	build := model.BuildFromID(buildID)
+	err := db.Get().First(&build).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false
+	} else if err != nil {
+		return true
+	}
+	switch build.Status {
+	case model.BuildRunning:
+		return build.Instance == e.config.Instance
+	case model.BuildScheduled:
+		return instance == e.config.Instance
+	}
+	return false
+}
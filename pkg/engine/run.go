@@ -10,16 +10,18 @@ import (
 )
 
 func (e *Engine) run(ctx *model.QueueContext, _runner *runner.Runner) error {
-	if err := createEnvSteps(ctx); err != nil {
+	if err := createEnvSteps(ctx, _runner.Workspace); err != nil {
 		e.logger.Error().Str("build_id", ctx.Build.ID()).Err(err).Msg("error during env steps creation")
 		return err
 	}
 
-	failed := false
+	failed, cancelled := false, false
 
 	for _, step := range ctx.Config.Steps {
 		if err := runStep(ctx, _runner, step); err != nil {
-			if err != ErrBuildCancelled {
+			if err == ErrBuildCancelled {
+				cancelled = true
+			} else {
 				e.logger.Warn().Str("build_id", ctx.Build.ID()).Err(err).Msgf("error during step [%s]", step.Name)
 				failed = true
 			}
@@ -36,7 +38,17 @@ func (e *Engine) run(ctx *model.QueueContext, _runner *runner.Runner) error {
 		return err
 	}
 
-	if failed {
+	if _runner.Workspace != nil {
+		// Reconcile removes the volume when this fails.
+		if err := _runner.Workspace.Remove(); err != nil {
+			e.logger.Warn().Str("build_id", ctx.Build.ID()).Err(err).Msg("error during workspace removal")
+		}
+	}
+
+	switch {
+	case cancelled:
+		return ErrBuildCancelled
+	case failed:
 		return runner.ErrBuildFailed
 	}
 	return nil
@@ -66,7 +78,12 @@ func runStep(ctx *model.QueueContext, _runner *runner.Runner, step model.Pipelin
 
 	fmt.Printf("\n### %s ###\n\n", step.Name)
 
-	err := _runner.Run(step.Commands)
+	var err error
+	if step.ImageBuild != nil {
+		err = buildImage(ctx, _runner, step, &buildStep)
+	} else {
+		err = _runner.Run(step.Commands)
+	}
 
 	buildStep.End()
 
@@ -2,7 +2,6 @@ package engine
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/gg-mike/ccli/pkg/db"
@@ -98,10 +97,10 @@ func newQueueContext(buildID string, start time.Time) (model.QueueContext, error
 	}
 	ctx.Repo = project.Repo
 
-	if !initMultiple(&ctx, &ctx.Secrets, "secrets", "project_name", "pipeline_name", "path") {
+	if !initScoped(&ctx, &ctx.Secrets, "secrets", model.ResolveSecrets) {
 		return ctx, ErrInvalidSecrets
 	}
-	if !initMultiple(&ctx, &ctx.Variables, "variables", "project_name", "pipeline_name", "path", "value") {
+	if !initScoped(&ctx, &ctx.Variables, "variables", model.ResolveVariables) {
 		return ctx, ErrInvalidVariables
 	}
 
@@ -118,18 +117,11 @@ func initSingle[T any](ctx *model.QueueContext, m *T, elem string) bool {
 	return true
 }
 
-func initMultiple[T any](ctx *model.QueueContext, multiple *[]T, elem string, fields ...string) bool {
-	selector := fmt.Sprintf("key, %s", strings.Join(fields, ", "))
-	agg := fmt.Sprintf("key, %s", strings.Join(getAgg(fields), ", "))
+func initScoped[T any](ctx *model.QueueContext, multiple *[]T, elem string, resolve func(*gorm.DB, string, string) ([]T, error)) bool {
+	var err error
+	*multiple, err = resolve(db.Get(), ctx.Build.ProjectName, ctx.Build.PipelineName)
 
-	subQuery := db.Get().Table(elem).
-		Select(selector).
-		Order("key, project_name, pipeline_name")
-
-	output, ok := getOutput(db.Get().Table("(?) as sq", subQuery).
-		Select(agg).
-		Group("key").
-		Scan(multiple).Error)
+	output, ok := getOutput(err)
 
 	ctx.Build.AppendLog(model.BuildLog{Command: fmt.Sprintf("[%s init]", elem), Output: output})
 	if !ok {
@@ -140,14 +132,6 @@ func initMultiple[T any](ctx *model.QueueContext, multiple *[]T, elem string, fi
 	return true
 }
 
-func getAgg(values []string) []string {
-	out := []string{}
-	for _, value := range values {
-		out = append(out, "(array_agg("+value+")::TEXT[])[1] as "+value)
-	}
-	return out
-}
-
 func getOutput(err error) (string, bool) {
 	switch err {
 	case nil:
@@ -2,26 +2,29 @@ package standalone
 
 import (
 	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/gg-mike/ccli/pkg/db"
-	"github.com/gg-mike/ccli/pkg/docker"
 	"github.com/gg-mike/ccli/pkg/engine/common"
+	"github.com/gg-mike/ccli/pkg/executor"
 	"github.com/gg-mike/ccli/pkg/log"
 	"github.com/gg-mike/ccli/pkg/model"
 	"github.com/gg-mike/ccli/pkg/runner"
-	"github.com/gg-mike/ccli/pkg/ssh"
 	"gorm.io/gorm"
 )
 
 type Binder struct {
 	onBind func(model.QueueContext, *runner.Runner)
 
-	logger log.Logger
+	instance string
+	logger   log.Logger
 }
 
-func NewBinder(logger log.Logger) *Binder {
+func NewBinder(logger log.Logger, instance string) *Binder {
 	return &Binder{
-		logger: logger.NewComponentLogger("binder"),
+		instance: instance,
+		logger:   logger.NewComponentLogger("binder"),
 	}
 }
 
@@ -37,43 +40,110 @@ func (b Binder) Bind() error {
 			return nil
 		}
 
-		for _, elem := range q {
+		strategies, err := loadStrategies(tx)
+		if err != nil {
+			return err
+		}
+
+		planner, err := common.LoadPlanner(tx, q)
+		if err != nil {
+			return err
+		}
+
+		reasons := map[string]string{}
+		for elem, ok := planner.Next(); ok; elem, ok = planner.Next() {
 			var workers []model.Worker
-			if err := tx.Model(&model.Worker{}).Where("status <> 'unreachable'").Find(&workers).Error; err != nil {
+			if err := tx.Model(&model.Worker{}).Where("status <> 'unreachable' AND NOT draining").Find(&workers).Error; err != nil {
 				return err
 			}
 
-			worker, err := SelectWorker(elem.Context.Config, workers)
+			worker, err := SelectWorker(elem.Context.Config, workers, strategies)
 
 			if err == ErrNoAvailableWorker {
-				return nil
+				reasons[elem.ID] = model.WaitNoWorker
+				break
 			} else if err == ErrNoAvailableWorkerForConfiguration {
+				reasons[elem.ID] = model.WaitNoWorkerForConfiguration
+				continue
+			} else if err == ErrNoFreeWorkerForConfiguration {
+				reasons[elem.ID] = model.WaitNoWorker
 				continue
 			}
 
 			b.logger.Debug().Str("step", "bind").Str("build", elem.ID).Str("worker", worker.Name).Msg("worker selected")
 
+			requested := elem.Context.Config.Resources.Requested()
+			allocated := worker.Allocated.Add(requested)
 			if db.Get().Model(&worker).UpdateColumns(map[string]any{
-				"active_builds": worker.ActiveBuilds + 1,
-				"status":        model.WorkerUsed}).Error != nil {
+				"active_builds":               worker.ActiveBuilds + 1,
+				"allocated_cpu":               allocated.CPU,
+				"allocated_memory":            allocated.Memory,
+				"allocated_ephemeral_storage": allocated.EphemeralStorage,
+				"last_bound_at":               time.Now(),
+				"status":                      model.WorkerUsed}).Error != nil {
 				return ErrUpdatingWorker
 			}
 
 			elem.Context.Build.AppendLog(model.BuildLog{Command: "[bind]", Output: "worker [" + worker.Name + "] bound"})
 			elem.Context.Build.WorkerName = sql.NullString{String: worker.Name, Valid: true}
+			elem.Context.Build.Requested = requested
+			elem.Context.Build.Instance = b.instance
 			elem.Context.Build.Status = model.BuildRunning
 			elem.Context.Build.End()
 
-			if db.Get().UpdateColumns(elem.Context.Build).Error != nil {
+			// Build canceled since the queue was read stays canceled, the slot
+			// taken on the worker is given back.
+			result := db.Get().Model(&elem.Context.Build).Where("status = ?", model.BuildScheduled).UpdateColumns(elem.Context.Build)
+			if result.Error != nil {
 				return common.ErrUpdatingBuild
 			}
+			if result.RowsAffected == 0 {
+				b.logger.Debug().Str("step", "bind").Str("build", elem.ID).Msg("build canceled before it was bound")
+				if err := b.Unbind(elem.Context.Build); err != nil {
+					return err
+				}
+				continue
+			}
 
-			_runner, err := getRunner(worker.IsStatic)(&elem, worker)
+			var _runner *runner.Runner
+			_executor, err := executor.Get(worker.ExecutorType())
+			if err == nil {
+				_runner, err = _executor.Prepare(executor.Job{
+					Context: &elem.Context,
+					Worker:  worker,
+					Report: func(message string) {
+						elem.Context.Build.AppendLog(model.BuildLog{Command: "[worker]", Output: message})
+						elem.Context.Build.End()
+						// Status is left out, the build may be canceled meanwhile.
+						if err := db.Get().Omit("status").UpdateColumns(elem.Context.Build).Error; err != nil {
+							b.logger.Error().Str("step", "bind").Str("build", elem.ID).Err(err).Msg("could not save worker progress")
+						}
+					},
+				})
+			}
 			if err != nil {
-				if err := db.Get().Model(&elem.Context.Build).UpdateColumn("status", model.BuildFailed).Error; err != nil {
+				elem.Context.Build.AppendLog(model.BuildLog{Command: "[bind]", Output: "worker setup failed: " + err.Error()})
+				elem.Context.Build.End()
+				if err := db.Get().Omit("status").UpdateColumns(elem.Context.Build).Error; err != nil {
+					return common.ErrUpdatingBuild
+				}
+				result := db.Get().Model(&elem.Context.Build).Where("status = ?", model.BuildRunning).UpdateColumn("status", model.BuildFailed)
+				if result.Error != nil {
+					return result.Error
+				}
+				// Failed build would otherwise be bound again on every bind.
+				if err := db.Get().Delete(&model.QueueElem{ID: elem.ID}).Error; err != nil {
 					return err
 				}
-				return err
+				// Build canceled during setup was already unbound by Cancel.
+				if result.RowsAffected != 0 {
+					if err := b.Unbind(elem.Context.Build); err != nil {
+						return err
+					}
+				}
+				// Only this build failed, the rest of the queue may still be bound.
+				b.logger.Error().Str("step", "bind").Str("build", elem.ID).Str("worker", worker.Name).Err(err).Msg("worker setup failed")
+				continue
 			}
 
 			if err := db.Get().Delete(&model.QueueElem{ID: elem.ID}).Error; err != nil {
@@ -81,13 +151,15 @@ func (b Binder) Bind() error {
 			}
 
 			b.logger.Debug().Str("step", "bind").Str("build", elem.ID).Str("worker", worker.Name).Msg("worker bound")
+			planner.Bound(elem)
 			go b.onBind(elem.Context, _runner)
 		}
-		return nil
+		return planner.RecordWaits(tx, reasons, model.WaitNoWorker)
 	})
 }
 
-func (b Binder) Unbind(workerName string) error {
+func (b Binder) Unbind(build model.Build) error {
+	workerName := build.WorkerName.String
 	return db.Get().Transaction(func(tx *gorm.DB) error {
 		b.logger.Debug().Str("name", workerName).Msg("decrement active builds on bound worker")
 		var worker model.Worker
@@ -95,10 +167,19 @@ func (b Binder) Unbind(workerName string) error {
 			b.logger.Error().Str("name", workerName).Err(err).Msg("could not decrement active builds counter")
 			return err
 		}
-		if err := tx.Model(&worker).UpdateColumn("active_builds", worker.ActiveBuilds-1).Error; err != nil {
+		allocated := worker.Allocated.Sub(build.Requested)
+		if err := tx.Model(&worker).UpdateColumns(map[string]any{
+			"active_builds":               worker.ActiveBuilds - 1,
+			"allocated_cpu":               allocated.CPU,
+			"allocated_memory":            allocated.Memory,
+			"allocated_ephemeral_storage": allocated.EphemeralStorage,
+		}).Error; err != nil {
 			b.logger.Error().Str("name", workerName).Err(err).Msg("could not decrement active builds counter")
 			return err
 		}
+		if worker.Draining && worker.ActiveBuilds == 1 {
+			b.logger.Info().Str("name", workerName).Msg("draining worker is empty")
+		}
 		if worker.ActiveBuilds == 0 {
 			if err := tx.Model(&worker).UpdateColumn("status", model.WorkerIdle).Error; err != nil {
 				b.logger.Error().Str("name", workerName).Err(err).Msg("could not change status")
@@ -109,22 +190,77 @@ func (b Binder) Unbind(workerName string) error {
 	})
 }
 
-func (b *Binder) SetOnBind(callback func(model.QueueContext, *runner.Runner)) {
-	b.onBind = callback
-}
+func (b Binder) Reconcile(keep func(buildID, instance string) bool) error {
+	var workers []model.Worker
+	if err := db.Get().Find(&workers).Error; err != nil {
+		return err
+	}
+	var running []model.Build
+	if err := db.Get().Where(&model.Build{Status: model.BuildRunning}).Find(&running).Error; err != nil {
+		return err
+	}
+	active, allocated := map[string]int{}, map[string]model.ResourceUnits{}
+	for _, build := range running {
+		if build.WorkerName.Valid {
+			active[build.WorkerName.String]++
+			allocated[build.WorkerName.String] = allocated[build.WorkerName.String].Add(build.Requested)
+		}
+	}
 
-func getRunner(isStatic bool) func(*model.QueueElem, model.Worker) (*runner.Runner, error) {
-	if isStatic {
-		return func(_ *model.QueueElem, w model.Worker) (*runner.Runner, error) {
-			pk, err := w.PK()
+	errs := []error{}
+	for _, worker := range workers {
+		_executor, err := executor.Get(worker.ExecutorType())
+		if err != nil {
+			errs = append(errs, err)
+		} else if reconciler, ok := _executor.(executor.Reconciler); ok && worker.Status != model.WorkerUnreachable {
+			removed, err := reconciler.RemoveOrphans(worker, keep)
+			if removed > 0 {
+				b.logger.Info().Str("name", worker.Name).Int("removed", removed).Msg("removed orphaned resources")
+			}
 			if err != nil {
-				return &runner.Runner{}, err
+				errs = append(errs, err)
 			}
+		}
 
-			return ssh.NewRunner(w.Username, w.Address, pk)
+		if worker.ActiveBuilds == active[worker.Name] && worker.Allocated == allocated[worker.Name] {
+			continue
+		}
+		b.logger.Warn().Str("name", worker.Name).Int("from", worker.ActiveBuilds).Int("to", active[worker.Name]).Msg("repairing active builds counter")
+		columns := map[string]any{
+			"active_builds":               active[worker.Name],
+			"allocated_cpu":               allocated[worker.Name].CPU,
+			"allocated_memory":            allocated[worker.Name].Memory,
+			"allocated_ephemeral_storage": allocated[worker.Name].EphemeralStorage,
+		}
+		if worker.Status != model.WorkerUnreachable {
+			columns["status"] = model.WorkerIdle
+			if active[worker.Name] > 0 {
+				columns["status"] = model.WorkerUsed
+			}
+		}
+		if err := db.Get().Model(&worker).UpdateColumns(columns).Error; err != nil {
+			errs = append(errs, err)
 		}
 	}
-	return func(qe *model.QueueElem, w model.Worker) (*runner.Runner, error) {
-		return docker.NewRunner(w.Address, qe.Context.Config.Image, qe.Context.Config.Privileged)
+	return errors.Join(errs...)
+}
+
+func (b *Binder) SetOnBind(callback func(model.QueueContext, *runner.Runner)) {
+	b.onBind = callback
+}
+
+func loadStrategies(tx *gorm.DB) (map[string]SelectionStrategy, error) {
+	var pools []model.WorkerPool
+	if err := tx.Find(&pools).Error; err != nil {
+		return map[string]SelectionStrategy{}, err
+	}
+	strategies := map[string]SelectionStrategy{}
+	for _, pool := range pools {
+		strategy, err := NewSelectionStrategy(pool.Strategy)
+		if err != nil {
+			return map[string]SelectionStrategy{}, err
+		}
+		strategies[pool.Name] = strategy
 	}
+	return strategies, nil
 }
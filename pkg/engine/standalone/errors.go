@@ -5,6 +5,7 @@ import "errors"
 var (
 	ErrNoAvailableWorker                 = errors.New("no worker is available")
 	ErrNoAvailableWorkerForConfiguration = errors.New("no worker is available for given configuration")
+	ErrNoFreeWorkerForConfiguration      = errors.New("all workers for given configuration are busy")
 
 	ErrUpdatingWorker = errors.New("error during worker update")
 )
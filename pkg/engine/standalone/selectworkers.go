@@ -6,43 +6,73 @@ import (
 	"github.com/gg-mike/ccli/pkg/model"
 )
 
-func SelectWorker(cfg model.PipelineConfig, workers []model.Worker) (model.Worker, error) {
+// SelectWorker picks the pool with the most free slots among workers matching
+// the configuration and selects a worker from it using the pool's strategy.
+func SelectWorker(cfg model.PipelineConfig, workers []model.Worker, strategies map[string]SelectionStrategy) (model.Worker, error) {
 	if len(workers) == 0 {
 		return model.Worker{}, ErrNoAvailableWorker
 	}
 
-	workers = filterWorkers(workers, cfg.System, cfg.Image)
+	requested := cfg.Resources.Requested()
+	workers = filterWorkers(workers, cfg.Target, cfg.System, cfg.Image, cfg.RunsOn, requested)
 	if len(workers) == 0 {
 		return model.Worker{}, ErrNoAvailableWorkerForConfiguration
 	}
+	workers = freeWorkers(workers, requested)
+	if len(workers) == 0 {
+		return model.Worker{}, ErrNoFreeWorkerForConfiguration
+	}
+
+	pools := map[string][]model.Worker{}
+	slots := map[string]int{}
+	for _, worker := range workers {
+		pools[worker.Pool] = append(pools[worker.Pool], worker)
+		slots[worker.Pool] += free(worker)
+	}
+	names := make([]string, 0, len(pools))
+	for name := range pools {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if slots[names[i]] != slots[names[j]] {
+			return slots[names[i]] > slots[names[j]]
+		}
+		return names[i] < names[j]
+	})
 
-	return sortWorkers(workers)[0], nil
+	strategy, ok := strategies[names[0]]
+	if !ok {
+		strategy = Spread{}
+	}
+	return strategy.Select(pools[names[0]]), nil
 }
 
-func filterWorkers(workers []model.Worker, system, image string) []model.Worker {
+// filterWorkers returns workers which can run the build, busy or not.
+func filterWorkers(workers []model.Worker, target, system, image string, runsOn model.LabelSelector, requested model.ResourceUnits) []model.Worker {
 	filteredWorkers := []model.Worker{}
 	for _, worker := range workers {
-		if worker.ActiveBuilds < worker.Capacity &&
-			(system != "" && worker.IsStatic && worker.System == system) ||
-			(image != "" && !worker.IsStatic) {
+		if !worker.Serves(target) || !worker.Accommodates(requested) {
+			continue
+		}
+		// Cluster places the pod on a matching node itself.
+		if worker.ExecutorType() != model.WorkerKubernetes && !runsOn.Matches(worker.Labels) {
+			continue
+		}
+		if (worker.IsStatic && system != "" && worker.System == system) ||
+			(!worker.IsStatic && image != "") {
 			filteredWorkers = append(filteredWorkers, worker)
 		}
 	}
 	return filteredWorkers
 }
 
-// First element after sorting should be the best candidate
-func sortWorkers(workers []model.Worker) []model.Worker {
-	sort.Slice(workers, func(i, j int) bool {
-		iw, jw := workers[i], workers[j]
-		switch {
-		case iw.Strategy != jw.Strategy:
-			return iw.Strategy > jw.Strategy
-		case iw.ActiveBuilds != jw.ActiveBuilds:
-			return iw.ActiveBuilds < jw.ActiveBuilds
-		default:
-			return iw.Name < jw.Name
+// freeWorkers returns workers with a free slot and resources for the build.
+func freeWorkers(workers []model.Worker, requested model.ResourceUnits) []model.Worker {
+	free := []model.Worker{}
+	for _, worker := range workers {
+		if worker.Fits(requested) {
+			free = append(free, worker)
 		}
-	})
-	return workers
+	}
+	return free
 }
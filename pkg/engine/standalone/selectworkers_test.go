@@ -0,0 +1,159 @@
+package standalone
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gg-mike/ccli/pkg/model"
+)
+
+func static(name, pool string, active, capacity int) model.Worker {
+	return model.Worker{Name: name, Pool: pool, IsStatic: true, System: "linux", ActiveBuilds: active, Capacity: capacity}
+}
+
+func TestSelectWorker(t *testing.T) {
+	linux := model.PipelineConfig{System: "linux"}
+	withResources := func(w model.Worker, cpu string) model.Worker {
+		w.Resources = model.ResourceList{CPU: cpu}
+		return w
+	}
+	allocated := func(w model.Worker, cpu string) model.Worker {
+		w.Allocated = model.ResourceList{CPU: cpu}.Units()
+		return w
+	}
+	requesting := func(cpu string) model.PipelineConfig {
+		cfg := linux
+		cfg.Resources = model.ResourceConfig{Requests: model.ResourceList{CPU: cpu}}
+		return cfg
+	}
+	cluster := model.Worker{Name: "k8s", Type: model.WorkerKubernetes, Capacity: 10}
+
+	tests := []struct {
+		name       string
+		cfg        model.PipelineConfig
+		workers    []model.Worker
+		strategies map[string]SelectionStrategy
+		want       string
+		err        error
+	}{
+		{
+			name: "no workers",
+			cfg:  linux,
+			err:  ErrNoAvailableWorker,
+		},
+		{
+			name:    "full worker skipped",
+			cfg:     linux,
+			workers: []model.Worker{static("a", "p", 2, 2), static("b", "p", 3, 4)},
+			want:    "b",
+		},
+		{
+			name:    "zero capacity never selected",
+			cfg:     linux,
+			workers: []model.Worker{static("a", "p", 0, 0)},
+			err:     ErrNoAvailableWorkerForConfiguration,
+		},
+		{
+			name:    "all workers full",
+			cfg:     linux,
+			workers: []model.Worker{static("a", "p", 1, 1), static("b", "q", 4, 4)},
+			err:     ErrNoFreeWorkerForConfiguration,
+		},
+		{
+			name: "all matching workers full",
+			cfg:  linux,
+			workers: []model.Worker{
+				static("a", "p", 2, 2),
+				{Name: "win", Pool: "p", IsStatic: true, System: "windows", Capacity: 4},
+				cluster,
+			},
+			err: ErrNoFreeWorkerForConfiguration,
+		},
+		{
+			name:    "resources too small",
+			cfg:     requesting("2"),
+			workers: []model.Worker{withResources(static("a", "p", 0, 4), "1"), withResources(static("b", "p", 0, 4), "4")},
+			want:    "b",
+		},
+		{
+			name:    "resources never enough",
+			cfg:     requesting("8"),
+			workers: []model.Worker{withResources(static("a", "p", 0, 4), "4")},
+			err:     ErrNoAvailableWorkerForConfiguration,
+		},
+		{
+			name:    "resources in use",
+			cfg:     requesting("2"),
+			workers: []model.Worker{allocated(withResources(static("a", "p", 1, 4), "4"), "3")},
+			err:     ErrNoFreeWorkerForConfiguration,
+		},
+		{
+			name:    "other system",
+			cfg:     model.PipelineConfig{System: "windows"},
+			workers: []model.Worker{static("a", "p", 0, 1)},
+			err:     ErrNoAvailableWorkerForConfiguration,
+		},
+		{
+			name:    "pool with most free slots",
+			cfg:     linux,
+			workers: []model.Worker{static("a", "small", 0, 2), static("b", "big", 1, 2), static("c", "big", 0, 2)},
+			want:    "c",
+		},
+		{
+			name:    "full workers do not count to pool",
+			cfg:     linux,
+			workers: []model.Worker{static("a", "p", 0, 2), static("b", "q", 1, 1), static("c", "q", 0, 1)},
+			want:    "a",
+		},
+		{
+			name:    "pool ties broken by name",
+			cfg:     linux,
+			workers: []model.Worker{static("a", "q", 0, 2), static("b", "p", 0, 2)},
+			want:    "b",
+		},
+		{
+			name:       "pool strategy applied",
+			cfg:        linux,
+			workers:    []model.Worker{static("a", "p", 0, 4), static("b", "p", 2, 4)},
+			strategies: map[string]SelectionStrategy{"p": BinPacking{}},
+			want:       "b",
+		},
+		{
+			name:       "spread without pool strategy",
+			cfg:        linux,
+			workers:    []model.Worker{static("a", "p", 2, 4), static("b", "p", 0, 4)},
+			strategies: map[string]SelectionStrategy{"q": BinPacking{}},
+			want:       "b",
+		},
+		{
+			name:    "kubernetes target",
+			cfg:     model.PipelineConfig{Target: model.TargetKubernetes, Image: "alpine"},
+			workers: []model.Worker{static("a", "p", 0, 4), cluster},
+			want:    "k8s",
+		},
+		{
+			name: "kubernetes target with runs_on",
+			cfg: model.PipelineConfig{Target: model.TargetKubernetes, Image: "alpine",
+				RunsOn: model.LabelSelector{MatchLabels: model.Labels{"arch": "amd64"}}},
+			workers: []model.Worker{cluster},
+			want:    "k8s",
+		},
+		{
+			name:    "standalone target skips clusters",
+			cfg:     model.PipelineConfig{Target: model.TargetStandalone, Image: "alpine"},
+			workers: []model.Worker{cluster},
+			err:     ErrNoAvailableWorkerForConfiguration,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectWorker(tt.cfg, tt.workers, tt.strategies)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("SelectWorker() error = %v, want %v", err, tt.err)
+			}
+			if got.Name != tt.want {
+				t.Errorf("SelectWorker() = %s, want %s", got.Name, tt.want)
+			}
+		})
+	}
+}
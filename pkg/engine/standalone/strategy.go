@@ -0,0 +1,124 @@
+package standalone
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/model"
+)
+
+// SelectionStrategy picks one worker out of non-empty list of candidates
+// which all have at least one free slot.
+type SelectionStrategy interface {
+	Select(workers []model.Worker) model.Worker
+}
+
+func NewSelectionStrategy(name string) (SelectionStrategy, error) {
+	switch name {
+	case model.StrategyBinPacking:
+		return BinPacking{}, nil
+	case model.StrategySpread, "":
+		return Spread{}, nil
+	case model.StrategyLRU:
+		return LeastRecentlyUsed{}, nil
+	case model.StrategyWeighted:
+		return NewWeighted(rand.New(rand.NewSource(time.Now().UnixNano()))), nil
+	default:
+		return nil, fmt.Errorf("unknown selection strategy [%s]", name)
+	}
+}
+
+// BinPacking fills up the busiest workers first, leaving others idle.
+type BinPacking struct{}
+
+func (BinPacking) Select(workers []model.Worker) model.Worker {
+	return best(workers, func(a, b model.Worker) bool {
+		return free(a) < free(b)
+	})
+}
+
+// Spread prefers workers with the lowest load relative to their capacity.
+type Spread struct{}
+
+func (Spread) Select(workers []model.Worker) model.Worker {
+	return best(workers, func(a, b model.Worker) bool {
+		return a.ActiveBuilds*b.Capacity < b.ActiveBuilds*a.Capacity
+	})
+}
+
+// LeastRecentlyUsed prefers workers which were bound the longest time ago,
+// workers that were never bound go first.
+type LeastRecentlyUsed struct{}
+
+func (LeastRecentlyUsed) Select(workers []model.Worker) model.Worker {
+	return best(workers, func(a, b model.Worker) bool {
+		switch {
+		case a.LastBoundAt.Valid != b.LastBoundAt.Valid:
+			return !a.LastBoundAt.Valid
+		default:
+			return a.LastBoundAt.Time.Before(b.LastBoundAt.Time)
+		}
+	})
+}
+
+// Weighted picks random worker with probability proportional to its
+// capacity. Workers of the same capacity share their weight and the least
+// loaded of them is picked.
+type Weighted struct {
+	rand *rand.Rand
+}
+
+func NewWeighted(r *rand.Rand) Weighted {
+	return Weighted{rand: r}
+}
+
+func (s Weighted) Select(workers []model.Worker) model.Worker {
+	groups := map[int][]model.Worker{}
+	capacities := []int{}
+	total := 0
+	for _, worker := range sorted(workers) {
+		if free(worker) <= 0 {
+			continue
+		}
+		if _, ok := groups[worker.Capacity]; !ok {
+			capacities = append(capacities, worker.Capacity)
+		}
+		groups[worker.Capacity] = append(groups[worker.Capacity], worker)
+		total += worker.Capacity
+	}
+	if total <= 0 {
+		return sorted(workers)[0]
+	}
+	sort.Ints(capacities)
+	n := s.rand.Intn(total)
+	for _, capacity := range capacities {
+		weight := capacity * len(groups[capacity])
+		if n < weight {
+			return Spread{}.Select(groups[capacity])
+		}
+		n -= weight
+	}
+	return Spread{}.Select(groups[capacities[len(capacities)-1]])
+}
+
+func free(worker model.Worker) int {
+	return worker.Capacity - worker.ActiveBuilds
+}
+
+// best returns first worker according to less, ties are broken by name
+// so that the selection is deterministic.
+func best(workers []model.Worker, less func(a, b model.Worker) bool) model.Worker {
+	workers = sorted(workers)
+	sort.SliceStable(workers, func(i, j int) bool {
+		return less(workers[i], workers[j])
+	})
+	return workers[0]
+}
+
+func sorted(workers []model.Worker) []model.Worker {
+	out := append([]model.Worker{}, workers...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
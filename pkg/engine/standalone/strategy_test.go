@@ -0,0 +1,91 @@
+package standalone
+
+import (
+	"database/sql"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/model"
+)
+
+func worker(name string, active, capacity int) model.Worker {
+	return model.Worker{Name: name, ActiveBuilds: active, Capacity: capacity}
+}
+
+func boundAt(w model.Worker, t time.Time) model.Worker {
+	w.LastBoundAt = sql.NullTime{Time: t, Valid: true}
+	return w
+}
+
+func TestStrategies(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		strategy SelectionStrategy
+		workers  []model.Worker
+		want     string
+	}{
+		{"binpack fills busiest", BinPacking{}, []model.Worker{worker("a", 0, 4), worker("b", 3, 4), worker("c", 1, 4)}, "b"},
+		{"binpack counts free slots", BinPacking{}, []model.Worker{worker("a", 1, 2), worker("b", 5, 8)}, "a"},
+		{"binpack ties by name", BinPacking{}, []model.Worker{worker("b", 1, 2), worker("a", 1, 2)}, "a"},
+		{"spread prefers lowest load", Spread{}, []model.Worker{worker("a", 2, 4), worker("b", 1, 4), worker("c", 3, 4)}, "b"},
+		{"spread relative to capacity", Spread{}, []model.Worker{worker("a", 1, 2), worker("b", 2, 8)}, "b"},
+		{"spread ties by name", Spread{}, []model.Worker{worker("c", 0, 1), worker("b", 0, 4)}, "b"},
+		{"lru never bound first", LeastRecentlyUsed{}, []model.Worker{boundAt(worker("a", 0, 1), now), worker("b", 0, 1)}, "b"},
+		{"lru oldest bind", LeastRecentlyUsed{}, []model.Worker{boundAt(worker("a", 0, 1), now), boundAt(worker("b", 0, 1), now.Add(-time.Hour))}, "b"},
+		{"lru ties by name", LeastRecentlyUsed{}, []model.Worker{worker("b", 0, 1), worker("a", 0, 1)}, "a"},
+		{"weighted single worker with slots", NewWeighted(rand.New(rand.NewSource(1))), []model.Worker{worker("a", 2, 2), worker("b", 0, 1)}, "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.strategy.Select(tt.workers); got.Name != tt.want {
+				t.Errorf("Select() = %s, want %s", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeightedProportionalToCapacity(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers []model.Worker
+		want    map[string]float64
+	}{
+		{"idle small worker", []model.Worker{worker("small", 0, 1), worker("large", 2, 3)}, map[string]float64{"small": 0.25, "large": 0.75}},
+		{"equal capacity", []model.Worker{worker("a", 0, 2), worker("b", 0, 2)}, map[string]float64{"a": 1}},
+		{"equal capacity by load", []model.Worker{worker("a", 1, 2), worker("b", 0, 2), worker("c", 0, 4)}, map[string]float64{"b": 0.5, "c": 0.5}},
+		{"full workers left out", []model.Worker{worker("a", 2, 2), worker("b", 0, 1), worker("c", 0, 1)}, map[string]float64{"b": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWeighted(rand.New(rand.NewSource(1)))
+			const n = 8000
+			counts := map[string]int{}
+			for i := 0; i < n; i++ {
+				counts[s.Select(tt.workers).Name]++
+			}
+			for name, count := range counts {
+				if _, ok := tt.want[name]; !ok {
+					t.Errorf("worker %s selected %d times", name, count)
+				}
+			}
+			for name, share := range tt.want {
+				if got := float64(counts[name]) / n; got < share-0.03 || got > share+0.03 {
+					t.Errorf("worker %s selected in %.3f of cases, want %.2f", name, got, share)
+				}
+			}
+		})
+	}
+}
+
+func TestNewSelectionStrategy(t *testing.T) {
+	for _, name := range append(model.Strategies, "") {
+		if _, err := NewSelectionStrategy(name); err != nil {
+			t.Errorf("NewSelectionStrategy(%q) error = %v", name, err)
+		}
+	}
+	if _, err := NewSelectionStrategy("random"); err == nil {
+		t.Error("NewSelectionStrategy(\"random\") expected error")
+	}
+}
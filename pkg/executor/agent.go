@@ -0,0 +1,26 @@
+package executor
+
+import (
+	"github.com/gg-mike/ccli/pkg/agent"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gg-mike/ccli/pkg/runner"
+)
+
+// Agent runs builds through the agent connected from the build host.
+type Agent struct{}
+
+func (Agent) Prepare(job Job) (*runner.Runner, error) {
+	return agent.Get().NewRunner(job.Worker.Name)
+}
+
+func (Agent) RemoveWorker(worker model.Worker) error {
+	agent.Get().Disconnect(worker.Name)
+	return nil
+}
+
+func (Agent) Probe(worker model.Worker) error {
+	if !agent.Get().Connected(worker.Name) {
+		return agent.ErrNotConnected
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package executor_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/agent"
+	"github.com/gg-mike/ccli/pkg/executor"
+	"github.com/gg-mike/ccli/pkg/executor/executortest"
+	"github.com/gg-mike/ccli/pkg/log"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gorilla/websocket"
+)
+
+// agentServer serves endpoints the agent dials on the hub, every connection
+// belongs to the agent of the name.
+func agentServer(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/agents/connect", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		agent.Get().Accept(name, conn)
+	})
+	mux.HandleFunc("/api/agents/sessions/", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		agent.Get().Session(name, strings.TrimPrefix(r.URL.Path, "/api/agents/sessions/"), conn)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// hubOnce initializes the hub once for repeated runs of the test.
+var hubOnce sync.Once
+
+func TestAgentConformance(t *testing.T) {
+	hubOnce.Do(func() { agent.Init() })
+	defer agent.Shutdown()
+
+	server := agentServer(t, "agent")
+	state := filepath.Join(t.TempDir(), "agent.json")
+	data, _ := json.Marshal(agent.Credentials{Name: "agent", Key: "key"})
+	if err := os.WriteFile(state, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	client := agent.NewClient(log.NewLogger("ccli", "test", "error", ""), agent.Config{Server: server.URL, State: state})
+	go client.Run()
+	defer func() { <-client.Shutdown() }()
+
+	for deadline := time.Now().Add(5 * time.Second); !agent.Get().Connected("agent"); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("agent did not connect")
+		}
+	}
+
+	worker := model.Worker{Name: "agent", IsAgent: true}
+	if err := executortest.Check(executor.Agent{}, executor.Job{Worker: worker}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,89 @@
+package executor
+
+import (
+	"github.com/gg-mike/ccli/pkg/docker"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gg-mike/ccli/pkg/runner"
+	"gorm.io/gorm"
+)
+
+// Docker runs builds in containers on the worker's Docker daemon.
+type Docker struct{}
+
+func (Docker) Prepare(job Job) (*runner.Runner, error) {
+	config := job.Context.Config
+	registry := docker.Registry{}
+	if !config.Registry.Empty() {
+		password, err := config.Registry.Password(job.Context.Secrets)
+		if err != nil {
+			return &runner.Runner{}, err
+		}
+		registry = docker.Registry{Server: config.Registry.Server, Username: config.Registry.Username, Password: password}
+	}
+	requests, limits := config.Resources.Requests.Units(), config.Resources.Limits.Units()
+	return docker.NewRunner(job.Worker.Address, docker.RunnerConfig{
+		Image:      config.Image,
+		Privileged: config.Privileged,
+		PullPolicy: config.PullPolicy,
+		Registry:   registry,
+		Resources: docker.Resources{
+			CPURequest:       requests.CPU,
+			CPULimit:         limits.CPU,
+			MemoryRequest:    requests.Memory,
+			MemoryLimit:      limits.Memory,
+			EphemeralStorage: limits.EphemeralStorage,
+		},
+		Services: dockerServices(config.Services),
+		Workspace: docker.Workspace{
+			Name:   docker.WorkspaceName(job.Context.Build.ID()),
+			Path:   model.WorkspacePath,
+			Driver: config.Workspace.StorageClass,
+			Size:   config.Workspace.Bytes(),
+		},
+		Labels: docker.BuildLabels(job.Context.Build.Instance, job.Context.Build.ID()),
+		Report: job.Report,
+	})
+}
+
+func (Docker) CreateWorker(tx *gorm.DB, worker *model.Worker, input *model.WorkerInput) error {
+	return docker.NewClient(worker.Address)
+}
+
+func (Docker) UpdateWorker(tx *gorm.DB, prev model.Worker, worker *model.Worker, input *model.WorkerInput) error {
+	if prev.ExecutorType() == model.WorkerDocker {
+		if err := docker.DeleteClient(prev.Address); err != nil {
+			return err
+		}
+	}
+	return docker.NewClient(worker.Address)
+}
+
+func (Docker) RemoveWorker(worker model.Worker) error {
+	return docker.DeleteClient(worker.Address)
+}
+
+func (Docker) Probe(worker model.Worker) error {
+	return docker.Ping(worker.Address)
+}
+
+func (Docker) RemoveOrphans(worker model.Worker, keep func(buildID, instance string) bool) (int, error) {
+	return docker.RemoveOrphans(worker.Address, keep)
+}
+
+func dockerServices(configs []model.ServiceConfig) []docker.Service {
+	services := []docker.Service{}
+	for _, config := range configs {
+		// Validated when the pipeline was saved.
+		interval, timeout, _ := config.HealthCheck.Durations()
+		services = append(services, docker.Service{
+			Name:        config.Name,
+			Image:       config.Image,
+			Command:     config.Command,
+			Env:         config.EnvList(),
+			HealthCheck: config.HealthCheck.Command,
+			Interval:    interval,
+			Timeout:     timeout,
+		})
+	}
+	return services
+}
@@ -0,0 +1,103 @@
+package executor
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gg-mike/ccli/pkg/runner"
+)
+
+// Job is a build bound to the worker which needs its environment.
+type Job struct {
+	Context *model.QueueContext
+	Worker  model.Worker
+	// Report receives progress of the environment setup.
+	Report func(string)
+}
+
+// Executor prepares build environments on one type of worker. The returned
+// runner runs commands in the environment (Run), copies files in and out of
+// it (CopyIn, CopyOut) and tears it down (Shutdown).
+type Executor interface {
+	Prepare(job Job) (*runner.Runner, error)
+}
+
+// Reconciler is implemented by executors leaving resources on the worker
+// which may outlive the server, e.g. containers.
+type Reconciler interface {
+	RemoveOrphans(worker model.Worker, keep func(buildID, instance string) bool) (int, error)
+}
+
+// Prober is implemented by executors checking whether the worker host
+// responds, e.g. by connecting to it.
+type Prober interface {
+	Probe(worker model.Worker) error
+}
+
+// Executors may also implement hooks of workers (model.WorkerCreator,
+// model.WorkerSecrets, model.WorkerUpdater and model.WorkerRemover) called as
+// workers of their type are saved and deleted.
+func init() {
+	model.SetWorkerHooks(func(workerType string) any {
+		executor, err := Get(workerType)
+		if err != nil {
+			return nil
+		}
+		return executor
+	})
+}
+
+var (
+	mu        sync.RWMutex
+	executors = map[string]Executor{
+		model.WorkerDocker:     Docker{},
+		model.WorkerSSH:        SSH{},
+		model.WorkerAgent:      Agent{},
+		model.WorkerLocal:      Local{},
+		model.WorkerKubernetes: NewKubernetes(),
+	}
+)
+
+// Register makes the executor serve workers of the type, replacing previously
+// registered one.
+func Register(workerType string, executor Executor) {
+	mu.Lock()
+	defer mu.Unlock()
+	executors[workerType] = executor
+}
+
+func Get(workerType string) (Executor, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	executor, ok := executors[workerType]
+	if !ok {
+		return nil, fmt.Errorf("no executor registered for worker type [%s]", workerType)
+	}
+	return executor, nil
+}
+
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	types := []string{}
+	for workerType := range executors {
+		types = append(types, workerType)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// Probe checks health of the worker by its executor, workers of executors
+// without probe are healthy.
+func Probe(worker model.Worker) error {
+	executor, err := Get(worker.ExecutorType())
+	if err != nil {
+		return err
+	}
+	if prober, ok := executor.(Prober); ok {
+		return prober.Probe(worker)
+	}
+	return nil
+}
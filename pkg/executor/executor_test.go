@@ -0,0 +1,95 @@
+package executor_test
+
+import (
+	"testing"
+
+	"github.com/gg-mike/ccli/pkg/executor"
+	"github.com/gg-mike/ccli/pkg/model"
+	"gorm.io/gorm"
+)
+
+// updateTx returns transaction the handler passes to update hooks. Hooks of
+// the workers in tests must not reach vault, Docker or database.
+func updateTx(prev model.Worker) *gorm.DB {
+	tx := &gorm.DB{Statement: &gorm.Statement{}}
+	return tx.InstanceSet("prev", prev).InstanceSet("input", model.WorkerInput{})
+}
+
+func TestWorkerUpdateHooks(t *testing.T) {
+	local := model.Worker{Name: "local", Type: model.WorkerLocal, IsStatic: true, Status: model.WorkerIdle}
+	cluster := model.Worker{Name: "k8s", Type: model.WorkerKubernetes, Cluster: model.ClusterConfig{Mode: "outer", Namespace: "ci"}}
+	tests := []struct {
+		name    string
+		prev    model.Worker
+		next    model.Worker
+		wantErr bool
+	}{
+		{"local", local, local, false},
+		{"local with missing shell", local, model.Worker{Name: "local", Type: model.WorkerLocal, IsStatic: true, Local: model.LocalConfig{Shell: "ccli-no-such-shell"}}, true},
+		{"agent", model.Worker{Name: "agent", IsAgent: true}, model.Worker{Name: "agent", IsAgent: true}, false},
+		{"kubernetes", cluster, cluster, false},
+		{"kubernetes without namespace", cluster, model.Worker{Name: "k8s", Type: model.WorkerKubernetes, Cluster: model.ClusterConfig{Mode: "outer"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.next.AfterUpdate(updateTx(tt.prev)); (err != nil) != tt.wantErr {
+				t.Errorf("AfterUpdate() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWorkerAfterCreateStoresOnlySSHKeys(t *testing.T) {
+	for _, worker := range []model.Worker{
+		{Name: "local", Type: model.WorkerLocal, IsStatic: true},
+		{Name: "agent", IsAgent: true},
+		{Name: "k8s", Type: model.WorkerKubernetes},
+	} {
+		if err := worker.AfterCreate(updateTx(model.Worker{})); err != nil {
+			t.Errorf("AfterCreate() of %s worker error = %v", worker.ExecutorType(), err)
+		}
+	}
+}
+
+func TestHostKeyOnlyOnSSH(t *testing.T) {
+	for _, worker := range []model.Worker{
+		{Name: "local", Type: model.WorkerLocal, IsStatic: true},
+		{Name: "docker"},
+		{Name: "agent", IsAgent: true, IsStatic: true},
+		{Name: "k8s", Type: model.WorkerKubernetes},
+	} {
+		if err := executor.ConfirmHostKey(nil, &worker, ""); err == nil {
+			t.Errorf("host key of %s worker confirmed", worker.ExecutorType())
+		}
+		if err := executor.RotateHostKey(nil, &worker, ""); err == nil {
+			t.Errorf("host key of %s worker rotated", worker.ExecutorType())
+		}
+	}
+}
+
+func TestKubernetesWorkerRequiresKubeconfig(t *testing.T) {
+	outer := model.Worker{Name: "k8s", Type: model.WorkerKubernetes, Cluster: model.ClusterConfig{Mode: "outer", Namespace: "ci"}}
+	inner := model.Worker{Name: "k8s", Type: model.WorkerKubernetes, Cluster: model.ClusterConfig{Mode: "inner", Namespace: "ci"}}
+	withKubeconfig := func(tx *gorm.DB) *gorm.DB {
+		return tx.InstanceSet("input", model.WorkerInput{Kubeconfig: "apiVersion: v1"})
+	}
+	tests := []struct {
+		name    string
+		hook    func() error
+		wantErr bool
+	}{
+		{"create outer", func() error { return outer.BeforeCreate(withKubeconfig(updateTx(model.Worker{}))) }, false},
+		{"create outer without kubeconfig", func() error { return outer.BeforeCreate(updateTx(model.Worker{})) }, true},
+		{"create inner", func() error { return inner.BeforeCreate(updateTx(model.Worker{})) }, false},
+		{"update outer keeps kubeconfig", func() error { return outer.AfterUpdate(updateTx(outer)) }, false},
+		{"update inner to outer without kubeconfig", func() error { return outer.AfterUpdate(updateTx(inner)) }, true},
+		{"update local to outer without kubeconfig", func() error { return outer.AfterUpdate(updateTx(model.Worker{Name: "k8s", Type: model.WorkerLocal})) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.hook(); (err != nil) != tt.wantErr {
+				t.Errorf("hook error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
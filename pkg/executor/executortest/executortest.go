@@ -0,0 +1,93 @@
+// Package executortest checks that executors keep the runner contract the
+// engine relies on, every executor registered in the server should pass it.
+package executortest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gg-mike/ccli/pkg/executor"
+	"github.com/gg-mike/ccli/pkg/runner"
+)
+
+// Check prepares environment for the job and exercises it: commands, their
+// output and failure, copying files in and out and teardown. All violations
+// are returned joined.
+func Check(e executor.Executor, job executor.Job) error {
+	if job.Report == nil {
+		job.Report = func(string) {}
+	}
+	_runner, err := e.Prepare(job)
+	if err != nil {
+		return fmt.Errorf("prepare: %w", err)
+	}
+
+	errs := []error{}
+	check := func(name string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	check("run", checkRun(_runner))
+	check("failure", checkFailure(_runner))
+	check("state", checkState(_runner))
+	check("copy", checkCopy(_runner))
+	check("shutdown", _runner.Shutdown())
+	return errors.Join(errs...)
+}
+
+func checkRun(r *runner.Runner) error {
+	cmds, out := 0, []string{}
+	r.OnCmd = func(string, int, int) { cmds++ }
+	r.OnOut = func(line string) { out = append(out, line) }
+	if err := r.Run([]string{"echo first", "sh -c 'echo second >&2'"}); err != nil {
+		return err
+	}
+	if cmds != 2 {
+		return fmt.Errorf("OnCmd called %d times, expected 2", cmds)
+	}
+	if strings.Join(out, "\n") != "first\nsecond" {
+		return fmt.Errorf("unexpected output %q", out)
+	}
+	return nil
+}
+
+func checkFailure(r *runner.Runner) error {
+	r.OnCmd, r.OnOut = func(string, int, int) {}, func(string) {}
+	if err := r.Run([]string{"false"}); !errors.Is(err, runner.ErrBuildFailed) {
+		return fmt.Errorf("failing command returned %v, expected %v", err, runner.ErrBuildFailed)
+	}
+	return nil
+}
+
+// checkState verifies that commands share one shell session.
+func checkState(r *runner.Runner) error {
+	out := []string{}
+	r.OnCmd, r.OnOut = func(string, int, int) {}, func(line string) { out = append(out, line) }
+	if err := r.Run([]string{"export CCLI_CONFORMANCE=kept", "cd /", "echo $CCLI_CONFORMANCE $(pwd)"}); err != nil {
+		return err
+	}
+	if strings.Join(out, "\n") != "kept /" {
+		return fmt.Errorf("environment or work dir not kept between commands, got %q", out)
+	}
+	return nil
+}
+
+func checkCopy(r *runner.Runner) error {
+	content := bytes.Repeat([]byte("ccli \x00'\"\n"), 16*1024)
+	path := "/tmp/ccli-conformance"
+	if err := r.CopyIn(path, bytes.NewReader(content)); err != nil {
+		return fmt.Errorf("copy in: %w", err)
+	}
+	out := bytes.Buffer{}
+	if err := r.CopyOut(path, &out); err != nil {
+		return fmt.Errorf("copy out: %w", err)
+	}
+	if !bytes.Equal(out.Bytes(), content) {
+		return fmt.Errorf("copied out %d bytes differ from %d copied in", out.Len(), len(content))
+	}
+	r.OnCmd, r.OnOut = func(string, int, int) {}, func(string) {}
+	return r.Run([]string{"rm -f " + path})
+}
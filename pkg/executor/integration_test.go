@@ -0,0 +1,146 @@
+//go:build integration
+
+package executor_test
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/gg-mike/ccli/pkg/docker"
+	"github.com/gg-mike/ccli/pkg/executor"
+	"github.com/gg-mike/ccli/pkg/executor/executortest"
+	"github.com/gg-mike/ccli/pkg/kubernetes"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gg-mike/ccli/pkg/ssh"
+	"github.com/gg-mike/ccli/pkg/vault"
+)
+
+// Executors with remote workers are checked against real infrastructure
+// given by the environment, run with: go test -tags integration ./pkg/executor/
+
+// testImage is the image of container and pod executors, CCLI_TEST_IMAGE
+// overrides it.
+func testImage() string {
+	if image := os.Getenv("CCLI_TEST_IMAGE"); image != "" {
+		return image
+	}
+	return "alpine"
+}
+
+// env returns the variables or skips the test when any of them is not set.
+func env(t *testing.T, names ...string) []string {
+	t.Helper()
+	values := []string{}
+	for _, name := range names {
+		value := os.Getenv(name)
+		if value == "" {
+			t.Skipf("%s not set", name)
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+var vaultOnce sync.Once
+
+// initVault connects vault once for all tests keeping worker secrets in it.
+func initVault(t *testing.T, url, token string) {
+	t.Helper()
+	var err error
+	vaultOnce.Do(func() { err = vault.Init(vault.Config{Url: url, Token: token}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func job(worker model.Worker) executor.Job {
+	return executor.Job{
+		Context: &model.QueueContext{
+			Build:  model.Build{ProjectName: "conformance", PipelineName: worker.Type, Number: 1, Instance: "conformance"},
+			Config: model.PipelineConfig{Image: testImage()},
+		},
+		Worker: worker,
+	}
+}
+
+// TestSSHConformance needs the worker address (host:port), user and path to
+// its private key, and vault the key is stored in for the test.
+func TestSSHConformance(t *testing.T) {
+	vars := env(t, "CCLI_TEST_SSH_ADDR", "CCLI_TEST_SSH_USER", "CCLI_TEST_SSH_KEY", "CCLI_TEST_VAULT_URL", "CCLI_TEST_VAULT_TOKEN")
+	address, username, keyPath := vars[0], vars[1], vars[2]
+	privateKey, err := os.ReadFile(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initVault(t, vars[3], vars[4])
+	if err := ssh.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ssh.Shutdown() })
+
+	hostKey, _, err := ssh.ScanHostKey(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worker := model.Worker{
+		Name:         "ccli-conformance-ssh",
+		Type:         model.WorkerSSH,
+		IsStatic:     true,
+		Address:      address,
+		Username:     username,
+		HostKey:      hostKey,
+		HostKeyState: model.HostKeyTrusted,
+	}
+	if err := vault.SetStr(worker.Name, string(privateKey)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { vault.Del(worker.Name) })
+
+	if err := executortest.Check(executor.SSH{}, job(worker)); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestDockerConformance needs the daemon host, e.g. unix:///var/run/docker.sock.
+func TestDockerConformance(t *testing.T) {
+	host := env(t, "CCLI_TEST_DOCKER_HOST")[0]
+	if err := docker.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { docker.Shutdown() })
+
+	worker := model.Worker{Name: "ccli-conformance-docker", Type: model.WorkerDocker, Address: host}
+	if err := executortest.Check(executor.Docker{}, job(worker)); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestKubernetesConformance needs the namespace pods are created in and vault
+// the kubeconfig is stored in for the test, the cluster is taken from
+// CCLI_TEST_KUBECONFIG or the default kubeconfig.
+func TestKubernetesConformance(t *testing.T) {
+	vars := env(t, "CCLI_TEST_KUBE_NAMESPACE", "CCLI_TEST_VAULT_URL", "CCLI_TEST_VAULT_TOKEN")
+	kubeconfig, err := kubernetes.ReadKubeconfig(os.Getenv("CCLI_TEST_KUBECONFIG"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	initVault(t, vars[1], vars[2])
+
+	worker := model.Worker{
+		Name: "ccli-conformance-kubernetes",
+		Type: model.WorkerKubernetes,
+		Cluster: model.ClusterConfig{
+			Mode:      "outer",
+			Namespace: vars[0],
+		},
+	}
+	if err := vault.SetStr(worker.Name, string(kubeconfig)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { vault.Del(worker.Name) })
+
+	if err := executortest.Check(executor.NewKubernetes(), job(worker)); err != nil {
+		t.Error(err)
+	}
+}
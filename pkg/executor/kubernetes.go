@@ -0,0 +1,154 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gg-mike/ccli/pkg/kubernetes"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gg-mike/ccli/pkg/runner"
+	"github.com/gg-mike/ccli/pkg/vault"
+	"gorm.io/gorm"
+)
+
+var errMissingKubeconfig = errors.New("missing kubeconfig of outer cluster")
+
+// Kubernetes runs builds as pods in the namespace of the worker's cluster.
+type Kubernetes struct {
+	mu      *sync.Mutex
+	clients map[string]cachedClient
+}
+
+type cachedClient struct {
+	cluster    model.ClusterConfig
+	kubeconfig string
+	client     *kubernetes.Client
+}
+
+func NewKubernetes() Kubernetes {
+	return Kubernetes{mu: &sync.Mutex{}, clients: map[string]cachedClient{}}
+}
+
+// client returns client of the worker's cluster, connecting again when the
+// cluster of the worker or its kubeconfig changed.
+func (k Kubernetes) client(worker model.Worker) (*kubernetes.Client, error) {
+	if err := worker.Cluster.Validate(); err != nil {
+		return nil, err
+	}
+	kubeconfig := ""
+	if worker.Cluster.Mode == "outer" {
+		var err error
+		if kubeconfig, err = worker.Kubeconfig(); err != nil {
+			return nil, fmt.Errorf("error during retrieving kubeconfig: %v", err)
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if cached, ok := k.clients[worker.Name]; ok && cached.cluster == worker.Cluster && cached.kubeconfig == kubeconfig {
+		return cached.client, nil
+	}
+	client, err := kubernetes.Connect(worker.Cluster.Mode, []byte(kubeconfig))
+	if err != nil {
+		return nil, err
+	}
+	if worker.Cluster.BuilderImage != "" {
+		client.BuilderImage = worker.Cluster.BuilderImage
+	}
+	k.clients[worker.Name] = cachedClient{cluster: worker.Cluster, kubeconfig: kubeconfig, client: client}
+	return client, nil
+}
+
+func (k Kubernetes) Prepare(job Job) (*runner.Runner, error) {
+	client, err := k.client(job.Worker)
+	if err != nil {
+		return &runner.Runner{}, err
+	}
+	registry, err := kubernetesRegistry(job.Context)
+	if err != nil {
+		return &runner.Runner{}, err
+	}
+	build := job.Context.Build
+	podName := strings.ReplaceAll(build.ID(), "/", "-")
+	owner := kubernetes.Owner{Instance: build.Instance, BuildID: build.ID()}
+	return client.NewRunner(job.Worker.Cluster.Namespace, podName, owner, job.Context.Config, registry, job.Report)
+}
+
+// CreateWorker requires kubeconfig of outer clusters in the input, files on
+// the server are never read for workers saved through the API.
+func (Kubernetes) CreateWorker(tx *gorm.DB, worker *model.Worker, input *model.WorkerInput) error {
+	if err := worker.Cluster.Validate(); err != nil {
+		return err
+	}
+	if worker.Cluster.Mode == "outer" && (input == nil || input.Kubeconfig == "") {
+		return errMissingKubeconfig
+	}
+	return nil
+}
+
+func (Kubernetes) StoreSecrets(worker model.Worker, input *model.WorkerInput) error {
+	if worker.Cluster.Mode != "outer" {
+		return nil
+	}
+	return vault.SetStr(worker.Name, input.Kubeconfig)
+}
+
+// UpdateWorker keeps the stored kubeconfig unless other one is given.
+func (k Kubernetes) UpdateWorker(tx *gorm.DB, prev model.Worker, worker *model.Worker, input *model.WorkerInput) error {
+	if err := worker.Cluster.Validate(); err != nil {
+		return err
+	}
+	stored := prev.ExecutorType() == model.WorkerKubernetes && prev.Cluster.Mode == "outer"
+	given := input != nil && input.Kubeconfig != ""
+	switch {
+	case worker.Cluster.Mode != "outer" && stored:
+		return k.RemoveWorker(prev)
+	case worker.Cluster.Mode != "outer":
+		return nil
+	case given:
+		return vault.SetStr(worker.Name, input.Kubeconfig)
+	case !stored:
+		return errMissingKubeconfig
+	}
+	return nil
+}
+
+func (k Kubernetes) RemoveWorker(worker model.Worker) error {
+	k.mu.Lock()
+	delete(k.clients, worker.Name)
+	k.mu.Unlock()
+	if worker.Cluster.Mode != "outer" {
+		return nil
+	}
+	return vault.Del(worker.Name)
+}
+
+func (k Kubernetes) RemoveOrphans(worker model.Worker, keep func(buildID, instance string) bool) (int, error) {
+	client, err := k.client(worker)
+	if err != nil {
+		return 0, err
+	}
+	return client.RemoveOrphans(worker.Cluster.Namespace, keep)
+}
+
+func (k Kubernetes) Probe(worker model.Worker) error {
+	client, err := k.client(worker)
+	if err != nil {
+		return err
+	}
+	return client.Ping()
+}
+
+func kubernetesRegistry(ctx *model.QueueContext) (kubernetes.Registry, error) {
+	config := ctx.Config.Registry
+	if config.Empty() {
+		return kubernetes.Registry{}, nil
+	}
+	password, err := config.Password(ctx.Secrets)
+	if err != nil {
+		return kubernetes.Registry{}, err
+	}
+	return kubernetes.Registry{Server: config.Server, Username: config.Username, Password: password}, nil
+}
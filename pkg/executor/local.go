@@ -0,0 +1,31 @@
+package executor
+
+import (
+	"github.com/gg-mike/ccli/pkg/local"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gg-mike/ccli/pkg/runner"
+	"gorm.io/gorm"
+)
+
+// Local runs builds in a sandboxed shell on the server host.
+type Local struct{}
+
+func (Local) Prepare(job Job) (*runner.Runner, error) {
+	return local.NewRunner(localOptions(job.Worker.Local))
+}
+
+func (Local) CreateWorker(tx *gorm.DB, worker *model.Worker, input *model.WorkerInput) error {
+	return local.Probe(localOptions(worker.Local))
+}
+
+func (Local) UpdateWorker(tx *gorm.DB, prev model.Worker, worker *model.Worker, input *model.WorkerInput) error {
+	return local.Probe(localOptions(worker.Local))
+}
+
+func (Local) Probe(worker model.Worker) error {
+	return local.Probe(localOptions(worker.Local))
+}
+
+func localOptions(c model.LocalConfig) local.Options {
+	return local.Options{Shell: c.Shell, User: c.User, Root: c.Root, Isolate: c.Isolate, Home: c.Home}
+}
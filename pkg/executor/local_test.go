@@ -0,0 +1,34 @@
+package executor_test
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/gg-mike/ccli/pkg/executor"
+	"github.com/gg-mike/ccli/pkg/executor/executortest"
+	"github.com/gg-mike/ccli/pkg/model"
+)
+
+func TestLocalConformance(t *testing.T) {
+	tests := []struct {
+		name   string
+		config model.LocalConfig
+	}{
+		{"default shell", model.LocalConfig{}},
+		{"bash", model.LocalConfig{Shell: "bash"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.config.Shell != "" {
+				if _, err := exec.LookPath(tt.config.Shell); err != nil {
+					t.Skipf("shell %s not installed", tt.config.Shell)
+				}
+			}
+			tt.config.Home = t.TempDir()
+			worker := model.Worker{Name: "local", Type: model.WorkerLocal, Local: tt.config}
+			if err := executortest.Check(executor.Local{}, executor.Job{Worker: worker}); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gg-mike/ccli/pkg/runner"
+	"github.com/gg-mike/ccli/pkg/scheduler"
+	"github.com/gg-mike/ccli/pkg/ssh"
+	"github.com/gg-mike/ccli/pkg/vault"
+	"gorm.io/gorm"
+)
+
+var ErrHostKeyNotPending = errors.New("no host key awaits confirmation")
+
+// SSH runs builds in a shell of the static worker reached over SSH.
+type SSH struct{}
+
+func (SSH) Prepare(job Job) (*runner.Runner, error) {
+	w := job.Worker
+	pk, err := w.PK()
+	if err != nil {
+		return &runner.Runner{}, err
+	}
+
+	_runner, err := ssh.NewRunner(w.Username, w.Address, pk, w.HostKey)
+	var mismatch *ssh.HostKeyMismatchError
+	if errors.As(err, &mismatch) {
+		if err := markUnreachable(w, err.Error()); err != nil {
+			return &runner.Runner{}, err
+		}
+	}
+	return _runner, err
+}
+
+func (SSH) CreateWorker(tx *gorm.DB, worker *model.Worker, input *model.WorkerInput) error {
+	if input == nil {
+		return errors.New("no private_key field given in instance")
+	}
+	if err := registerHostKey(worker, *input); err != nil {
+		return err
+	}
+	worker.Status, worker.StatusReason = testConnection(*worker, input.PrivateKey)
+	return nil
+}
+
+func (SSH) StoreSecrets(worker model.Worker, input *model.WorkerInput) error {
+	if input == nil {
+		return nil
+	}
+	return vault.SetStr(worker.Name, input.PrivateKey)
+}
+
+// UpdateWorker tests the connection with the (possibly new) private key, the
+// host key is kept, it is only changed by confirmation or rotation.
+func (SSH) UpdateWorker(tx *gorm.DB, prev model.Worker, worker *model.Worker, input *model.WorkerInput) error {
+	var privateKey string
+	if input != nil {
+		privateKey = input.PrivateKey
+	} else {
+		pKey, err := vault.GetStr(worker.Name)
+		if err != nil {
+			return fmt.Errorf("error during retrieving private key: %v", err)
+		}
+		privateKey = pKey
+	}
+	worker.HostKey = prev.HostKey
+	worker.HostKeyState = prev.HostKeyState
+	status, reason := testConnection(*worker, privateKey)
+	if status != model.WorkerUnreachable && prev.Status != model.WorkerUnreachable {
+		status = prev.Status
+	}
+	if err := tx.Model(worker).UpdateColumns(map[string]any{"status": status, "status_reason": reason}).Error; err != nil {
+		return err
+	}
+	if input == nil {
+		return nil
+	}
+	return vault.SetStr(worker.Name, privateKey)
+}
+
+func (SSH) RemoveWorker(worker model.Worker) error {
+	return vault.Del(worker.Name)
+}
+
+func (SSH) Probe(worker model.Worker) error {
+	if worker.HostKeyState == model.HostKeyPending {
+		return errors.New("host key [" + worker.HostKeyFP + "] awaits confirmation")
+	}
+	privateKey, err := worker.PK()
+	if err != nil {
+		return fmt.Errorf("error during retrieving private key: %v", err)
+	}
+	if worker.HostKey == "" {
+		return ssh.ErrHostKeyMissing
+	}
+	return ssh.CheckConnection(worker.Username, worker.Address, privateKey, worker.HostKey)
+}
+
+// ConfirmHostKey trusts the key recorded on registration, provided the
+// operator verified the same fingerprint out of band.
+func ConfirmHostKey(tx *gorm.DB, worker *model.Worker, fingerprint string) error {
+	if err := hasHostKey(*worker); err != nil {
+		return err
+	}
+	if worker.HostKeyState != model.HostKeyPending {
+		return ErrHostKeyNotPending
+	}
+	if fingerprint != worker.HostKeyFP {
+		return &ssh.HostKeyMismatchError{Expected: fingerprint, Actual: worker.HostKeyFP}
+	}
+	worker.HostKeyState = model.HostKeyTrusted
+	return refreshHostKey(tx, worker)
+}
+
+// RotateHostKey replaces the stored key with the one the host presents now,
+// optionally checking it against the expected fingerprint.
+func RotateHostKey(tx *gorm.DB, worker *model.Worker, fingerprint string) error {
+	if err := hasHostKey(*worker); err != nil {
+		return err
+	}
+	hostKey, actual, err := ssh.ScanHostKey(worker.Address)
+	if err != nil {
+		return err
+	}
+	if fingerprint != "" && fingerprint != actual {
+		return &ssh.HostKeyMismatchError{Expected: fingerprint, Actual: actual}
+	}
+	worker.HostKey = hostKey
+	worker.HostKeyFP = actual
+	worker.HostKeyState = model.HostKeyTrusted
+	return refreshHostKey(tx, worker)
+}
+
+func hasHostKey(worker model.Worker) error {
+	if t := worker.ExecutorType(); t != model.WorkerSSH {
+		return errors.New(t + " worker does not have a host key")
+	}
+	return nil
+}
+
+func refreshHostKey(tx *gorm.DB, worker *model.Worker) error {
+	privateKey, err := worker.PK()
+	if err != nil {
+		return fmt.Errorf("error during retrieving private key: %v", err)
+	}
+	worker.Status, worker.StatusReason = testConnection(*worker, privateKey)
+	if worker.Status == model.WorkerIdle && worker.ActiveBuilds > 0 {
+		worker.Status = model.WorkerUsed
+	}
+	if err := tx.Model(worker).UpdateColumns(map[string]any{
+		"host_key":       worker.HostKey,
+		"host_key_fp":    worker.HostKeyFP,
+		"host_key_state": worker.HostKeyState,
+		"status":         worker.Status,
+		"status_reason":  worker.StatusReason,
+	}).Error; err != nil {
+		return err
+	}
+	go scheduler.Get().ChangeInWorkers()
+	return nil
+}
+
+// registerHostKey records the key presented by the host, rejecting it when
+// it does not match the fingerprint pinned in the input.
+func registerHostKey(worker *model.Worker, input model.WorkerInput) error {
+	hostKey, fingerprint, err := ssh.ScanHostKey(worker.Address)
+	if err != nil {
+		if input.HostKeyFP != "" {
+			return err
+		}
+		return nil
+	}
+	if input.HostKeyFP != "" && input.HostKeyFP != fingerprint {
+		return &ssh.HostKeyMismatchError{Expected: input.HostKeyFP, Actual: fingerprint}
+	}
+
+	worker.HostKey = hostKey
+	worker.HostKeyFP = fingerprint
+	if input.HostKeyFP != "" || input.TrustHostKey {
+		worker.HostKeyState = model.HostKeyTrusted
+	} else {
+		worker.HostKeyState = model.HostKeyPending
+	}
+	return nil
+}
+
+// testConnection returns the status of the worker with the reason why it is
+// unreachable, if so.
+func testConnection(worker model.Worker, privateKey string) (string, string) {
+	switch {
+	case worker.HostKey == "":
+		return model.WorkerUnreachable, "host key unknown (rotate host key once the worker is up)"
+	case worker.HostKeyState == model.HostKeyPending:
+		return model.WorkerUnreachable, "host key [" + worker.HostKeyFP + "] awaits confirmation"
+	}
+	if err := ssh.CheckConnection(worker.Username, worker.Address, privateKey, worker.HostKey); err != nil {
+		return model.WorkerUnreachable, err.Error()
+	}
+	return model.WorkerIdle, ""
+}
+
+func markUnreachable(w model.Worker, reason string) error {
+	return db.Get().Model(&w).UpdateColumns(map[string]any{
+		"status":        model.WorkerUnreachable,
+		"status_reason": reason,
+	}).Error
+}
@@ -0,0 +1,168 @@
+package health
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/db"
+	"github.com/gg-mike/ccli/pkg/executor"
+	"github.com/gg-mike/ccli/pkg/log"
+	"github.com/gg-mike/ccli/pkg/model"
+	"github.com/gg-mike/ccli/pkg/scheduler"
+)
+
+// drainInterval is how often drain deadlines are enforced, independent of
+// the health check interval so that disabling health checks keeps them.
+const drainInterval = 15 * time.Second
+
+type Config struct {
+	Interval          time.Duration
+	FailureThreshold  int
+	RecoveryThreshold int
+}
+
+// Monitor periodically probes every worker and moves it to and from the
+// unreachable status once enough consecutive probes agree. It also cancels
+// builds left on draining workers past their deadline, even when probing is
+// disabled.
+type Monitor struct {
+	config   Config
+	shutdown chan any
+	done     chan any
+
+	logger log.Logger
+}
+
+func NewMonitor(logger log.Logger, config Config) *Monitor {
+	if config.FailureThreshold < 1 {
+		config.FailureThreshold = 1
+	}
+	if config.RecoveryThreshold < 1 {
+		config.RecoveryThreshold = 1
+	}
+	return &Monitor{
+		config:   config,
+		shutdown: make(chan any),
+		done:     make(chan any),
+
+		logger: logger.NewComponentLogger("health"),
+	}
+}
+
+func (m *Monitor) Run() {
+	var healthTick <-chan time.Time
+	if m.config.Interval > 0 {
+		m.logger.Info().Str("interval", m.config.Interval.String()).Msg("starting worker health monitor")
+		ticker := time.NewTicker(m.config.Interval)
+		defer ticker.Stop()
+		healthTick = ticker.C
+	} else {
+		m.logger.Info().Msg("worker health checks disabled")
+	}
+	drainTicker := time.NewTicker(drainInterval)
+	defer drainTicker.Stop()
+
+	for {
+		select {
+		case <-healthTick:
+			m.check()
+		case <-drainTicker.C:
+			m.enforceDrain()
+		case <-m.shutdown:
+			close(m.done)
+			m.logger.Info().Msg("worker health monitor shutdown")
+			return
+		}
+	}
+}
+
+func (m *Monitor) Shutdown() chan any {
+	go func() { m.shutdown <- true }()
+	return m.done
+}
+
+func (m *Monitor) check() {
+	var workers []model.Worker
+	if err := db.Get().Find(&workers).Error; err != nil {
+		m.logger.Error().Err(err).Msg("could not list workers")
+		return
+	}
+
+	recovered := false
+	for _, worker := range workers {
+		ok, err := m.probe(worker)
+		if err != nil {
+			m.logger.Error().Str("name", worker.Name).Err(err).Msg("could not update worker health")
+		}
+		recovered = recovered || ok
+	}
+
+	if recovered {
+		go scheduler.Get().ChangeInWorkers()
+	}
+}
+
+// enforceDrain cancels builds left on draining workers past their deadline.
+func (m *Monitor) enforceDrain() {
+	var workers []model.Worker
+	if err := db.Get().Where("draining AND drain_by < ? AND active_builds > 0", time.Now()).Find(&workers).Error; err != nil {
+		m.logger.Error().Err(err).Msg("could not list draining workers")
+		return
+	}
+
+	for _, worker := range workers {
+		if !worker.DrainExpired(time.Now()) {
+			continue
+		}
+		m.logger.Warn().Str("name", worker.Name).Int("builds", worker.ActiveBuilds).Msg("drain deadline passed, canceling remaining builds")
+		canceled, err := worker.CancelBuilds(db.Get())
+		if err != nil {
+			m.logger.Error().Str("name", worker.Name).Err(err).Msg("could not cancel builds of draining worker")
+		}
+		for _, buildID := range canceled {
+			go scheduler.Get().Finished(buildID)
+		}
+	}
+}
+
+// probe updates health of the single worker and reports whether it came back
+// from the unreachable status.
+func (m *Monitor) probe(worker model.Worker) (bool, error) {
+	probeErr := executor.Probe(worker)
+
+	columns := map[string]any{}
+	recovered := false
+	if probeErr == nil {
+		columns["last_seen_at"] = sql.NullTime{Time: time.Now(), Valid: true}
+		columns["failures"] = 0
+		columns["successes"] = worker.Successes + 1
+		if worker.Status == model.WorkerUnreachable && worker.Successes+1 >= m.config.RecoveryThreshold {
+			columns["status"] = model.WorkerIdle
+			if worker.ActiveBuilds > 0 {
+				columns["status"] = model.WorkerUsed
+			}
+			columns["status_reason"] = ""
+			recovered = true
+			m.logger.Info().Str("name", worker.Name).Msg("worker is reachable again")
+		}
+	} else {
+		columns["failures"] = worker.Failures + 1
+		columns["successes"] = 0
+		if worker.Status == model.WorkerUnreachable || worker.Failures+1 >= m.config.FailureThreshold {
+			columns["status_reason"] = probeErr.Error()
+		}
+		if worker.Status != model.WorkerUnreachable && worker.Failures+1 >= m.config.FailureThreshold {
+			columns["status"] = model.WorkerUnreachable
+			m.logger.Warn().Str("name", worker.Name).Err(probeErr).Msg("worker became unreachable")
+		}
+	}
+
+	// Guard against concurrent status changes done by the binder meanwhile.
+	result := db.Get().Model(&model.Worker{}).
+		Where("name = ? AND status = ?", worker.Name, worker.Status).
+		UpdateColumns(columns)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return recovered && result.RowsAffected != 0, nil
+}
@@ -0,0 +1,200 @@
+package kubernetes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/runner"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	DefaultBuilderImage = "gcr.io/kaniko-project/executor:latest"
+	builderContainer    = "kaniko"
+)
+
+// imageBuilder builds images in rootless kaniko pods next to the worker pod,
+// the build context is streamed to kaniko's stdin.
+type imageBuilder struct {
+	client         Client
+	namespace      string
+	name           string
+	owner          Owner
+	registrySecret string
+	builds         int
+}
+
+func (b *imageBuilder) BuildImage(spec runner.ImageSpec, buildContext io.Reader, out func(string)) (string, error) {
+	b.builds++
+	name := fmt.Sprintf("%s-image-%d", b.name, b.builds)
+	pods := b.client.clientset.CoreV1().Pods(b.namespace)
+
+	pod := b.pod(name, spec)
+	if _, err := pods.Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := pods.Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+			out("could not delete builder pod: " + err.Error())
+		}
+	}()
+	if err := b.client.waitForPod(b.namespace, name, 5*time.Minute, out); err != nil {
+		return "", err
+	}
+
+	req := b.client.clientset.CoreV1().RESTClient().
+		Post().
+		Namespace(b.namespace).
+		Resource("pods").
+		Name(name).
+		SubResource("attach").
+		Param("container", builderContainer).
+		Param("stdin", "true").
+		Param("stdout", "true").
+		Param("stderr", "true").
+		Param("tty", "false")
+	attach, err := remotecommand.NewSPDYExecutor(b.client.config, "POST", req.URL())
+	if err != nil {
+		return "", err
+	}
+
+	// Kaniko reads the context from stdin as gzipped tar.
+	compressed, writer := io.Pipe()
+	go func() {
+		gz := gzip.NewWriter(writer)
+		_, err := io.Copy(gz, buildContext)
+		if err == nil {
+			err = gz.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+	output := &lineWriter{out: out}
+	err = attach.StreamWithContext(context.Background(), remotecommand.StreamOptions{
+		Stdin:  compressed,
+		Stdout: output,
+		Stderr: output,
+	})
+	compressed.Close()
+	output.flush()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrExecTerminated, err)
+	}
+
+	var terminated *corev1.ContainerStateTerminated
+	err = wait.PollUntilContextTimeout(context.Background(), time.Second, time.Minute, true, func(ctx context.Context) (bool, error) {
+		pod, err := pods.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == builderContainer {
+				terminated = status.State.Terminated
+			}
+		}
+		return terminated != nil, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("waiting for image builder: %w", err)
+	}
+	if terminated.ExitCode != 0 {
+		return "", fmt.Errorf("image builder exited with code %d: %s", terminated.ExitCode, terminated.Reason)
+	}
+	// Digest file points to the termination message.
+	return strings.TrimSpace(terminated.Message), nil
+}
+
+func (b *imageBuilder) pod(name string, spec runner.ImageSpec) *corev1.Pod {
+	args := []string{
+		"--context=tar://stdin",
+		"--dockerfile=" + spec.Dockerfile,
+		"--digest-file=" + corev1.TerminationMessagePathDefault,
+	}
+	for _, tag := range spec.Tags {
+		args = append(args, "--destination="+tag)
+	}
+	if !spec.Push {
+		args = append(args, "--no-push")
+	}
+	args = append(args, sortedArgs("--build-arg=", spec.BuildArgs)...)
+	args = append(args, sortedArgs("--label=", spec.Labels)...)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:      builderContainer,
+					Image:     b.client.BuilderImage,
+					Args:      args,
+					Stdin:     true,
+					StdinOnce: true,
+				},
+			},
+		},
+	}
+	if b.registrySecret != "" {
+		pod.Spec.Volumes = []corev1.Volume{{
+			Name: "registry",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: b.registrySecret,
+					Items:      []corev1.KeyToPath{{Key: corev1.DockerConfigJsonKey, Path: "config.json"}},
+				},
+			},
+		}}
+		pod.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{{Name: "registry", MountPath: "/kaniko/.docker"}}
+	}
+	b.owner.apply(&pod.ObjectMeta)
+	return pod
+}
+
+func sortedArgs(prefix string, values map[string]string) []string {
+	args := []string{}
+	for key, value := range values {
+		args = append(args, prefix+key+"="+value)
+	}
+	slices.Sort(args)
+	return args
+}
+
+// lineWriter passes written output to out line by line, stdout and stderr of
+// the builder may be written concurrently.
+type lineWriter struct {
+	mu  sync.Mutex
+	out func(string)
+	buf []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			return len(p), nil
+		}
+		w.out(strings.TrimRight(string(w.buf[:idx]), "\r"))
+		w.buf = w.buf[idx+1:]
+	}
+}
+
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) != 0 {
+		w.out(string(w.buf))
+		w.buf = nil
+	}
+}
@@ -1,6 +1,7 @@
 package kubernetes
 
 import (
+	"os"
 	"path/filepath"
 
 	"k8s.io/client-go/kubernetes"
@@ -10,8 +11,20 @@ import (
 )
 
 type Client struct {
-	clientset *kubernetes.Clientset
+	clientset kubernetes.Interface
 	config    *rest.Config
+
+	// BuilderImage is kaniko executor image used for image_build steps.
+	BuilderImage string
+}
+
+// Connect creates client of the cluster the server runs in (inner mode) or of
+// the one from kubeconfig content (outer mode).
+func Connect(mode string, kubeconfig []byte) (*Client, error) {
+	if mode == "inner" {
+		return NewInnerClient()
+	}
+	return NewOuterClient(kubeconfig)
 }
 
 func NewInnerClient() (*Client, error) {
@@ -22,25 +35,36 @@ func NewInnerClient() (*Client, error) {
 	return newClient(config)
 }
 
-func NewDefaultOuterClient() (*Client, error) {
-	return NewOuterClient(filepath.Join(homedir.HomeDir(), ".kube", "config"))
-}
-
-func NewOuterClient(kubeconfig string) (*Client, error) {
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+func NewOuterClient(kubeconfig []byte) (*Client, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
 	if err != nil {
 		return &Client{}, err
 	}
 	return newClient(config)
 }
 
+// ReadKubeconfig reads kubeconfig from the path, the default location when
+// empty.
+func ReadKubeconfig(path string) ([]byte, error) {
+	if path == "" {
+		path = filepath.Join(homedir.HomeDir(), ".kube", "config")
+	}
+	return os.ReadFile(path)
+}
+
 func newClient(config *rest.Config) (*Client, error) {
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return &Client{}, err
 	}
 	return &Client{
-		clientset: clientset,
-		config:    config,
+		clientset:    clientset,
+		config:       config,
+		BuilderImage: DefaultBuilderImage,
 	}, nil
 }
+
+func (client Client) Ping() error {
+	_, err := client.clientset.Discovery().ServerVersion()
+	return err
+}
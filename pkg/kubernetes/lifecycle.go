@@ -0,0 +1,153 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var (
+	ErrPodTimeout     = errors.New("pod did not become ready in time")
+	ErrWatchClosed    = errors.New("pod watch closed unexpectedly")
+	ErrExecTerminated = errors.New("exec stream terminated")
+)
+
+// Waiting reasons after which the container will not start without a change
+// of the pod spec.
+var terminalReasons = map[string]bool{
+	"ImagePullBackOff":           true,
+	"ErrImageNeverPull":          true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"CrashLoopBackOff":           true,
+	"RunContainerError":          true,
+}
+
+// waitForPod watches the pod and its events until all containers are ready,
+// progress is passed to report. Terminal failures end the wait early.
+func (client Client) waitForPod(namespace, name string, timeout time.Duration, report func(string)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	pods, err := client.clientset.CoreV1().Pods(namespace).Watch(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+	})
+	if err != nil {
+		return err
+	}
+	defer pods.Stop()
+
+	events, err := client.clientset.CoreV1().Events(namespace).Watch(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("involvedObject.name", name).String(),
+	})
+	if err != nil {
+		return err
+	}
+	defer events.Stop()
+
+	tracker := podTracker{report: report, seen: map[string]string{}}
+	for {
+		select {
+		case <-ctx.Done():
+			return ErrPodTimeout
+		case e, ok := <-events.ResultChan():
+			if !ok {
+				// Events are informative only, the pod watch decides.
+				events = watch.NewEmptyWatch()
+				continue
+			}
+			if event, ok := e.Object.(*corev1.Event); ok && e.Type != watch.Deleted {
+				report(fmt.Sprintf("event %s: %s", event.Reason, event.Message))
+			}
+		case e, ok := <-pods.ResultChan():
+			if !ok {
+				return ErrWatchClosed
+			}
+			if e.Type == watch.Deleted {
+				return errors.New("pod deleted before it became ready")
+			}
+			pod, ok := e.Object.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+			ready, err := tracker.update(pod)
+			if err != nil || ready {
+				return err
+			}
+		}
+	}
+}
+
+// podTracker reports changes of the pod status and decides whether the pod
+// is ready or failed.
+type podTracker struct {
+	report func(string)
+	seen   map[string]string
+}
+
+func (t *podTracker) update(pod *corev1.Pod) (bool, error) {
+	t.changed("phase", string(pod.Status.Phase))
+
+	switch pod.Status.Phase {
+	case corev1.PodFailed, corev1.PodSucceeded:
+		return false, fmt.Errorf("pod %s: %s %s", pod.Status.Phase, pod.Status.Reason, pod.Status.Message)
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+			t.changed("scheduled", condition.Reason+": "+condition.Message)
+		}
+	}
+
+	for _, status := range pod.Status.InitContainerStatuses {
+		if err := t.container("init container", status); err != nil {
+			return false, err
+		}
+		if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			return false, fmt.Errorf("init container %s failed with exit code %d: %s", status.Name, terminated.ExitCode, terminated.Reason)
+		}
+	}
+
+	ready := pod.Status.Phase == corev1.PodRunning
+	for _, status := range pod.Status.ContainerStatuses {
+		if err := t.container("container", status); err != nil {
+			return false, err
+		}
+		if terminated := status.State.Terminated; terminated != nil {
+			return false, fmt.Errorf("container %s terminated with exit code %d: %s", status.Name, terminated.ExitCode, terminated.Reason)
+		}
+		ready = ready && status.Ready
+	}
+	return ready, nil
+}
+
+func (t *podTracker) container(kind string, status corev1.ContainerStatus) error {
+	waiting := status.State.Waiting
+	if waiting == nil || waiting.Reason == "" {
+		return nil
+	}
+	message := waiting.Reason
+	if waiting.Message != "" {
+		message += ": " + waiting.Message
+	}
+	t.changed(kind+" "+status.Name, message)
+	if terminalReasons[waiting.Reason] {
+		return fmt.Errorf("%s %s: %s", kind, status.Name, message)
+	}
+	return nil
+}
+
+func (t *podTracker) changed(key, value string) {
+	if value == "" || t.seen[key] == value {
+		return
+	}
+	t.seen[key] = value
+	t.report(key + ": " + value)
+}
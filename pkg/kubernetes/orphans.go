@@ -0,0 +1,85 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Labels and annotations put on pods created for builds, build ID does not
+// fit label value syntax so it is kept in the annotation.
+const (
+	LabelManaged    = "ccli/managed"
+	LabelInstance   = "ccli/instance"
+	AnnotationBuild = "ccli/build"
+)
+
+// Owner identifies the server and the build the pod is created for.
+type Owner struct {
+	Instance string
+	BuildID  string
+}
+
+func (o Owner) apply(meta *metav1.ObjectMeta) {
+	meta.Labels = mergeMaps(meta.Labels, map[string]string{LabelManaged: "true", LabelInstance: o.Instance})
+	meta.Annotations = mergeMaps(meta.Annotations, map[string]string{AnnotationBuild: o.BuildID})
+}
+
+// RemoveOrphans deletes pods, registry secrets and workspace claims created
+// for builds in the namespace unless keep says they are still in use, returns
+// how many were deleted.
+func (client Client) RemoveOrphans(namespace string, keep func(buildID, instance string) bool) (int, error) {
+	pods, err := client.clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: LabelManaged + "=true",
+	})
+	if err != nil {
+		return 0, err
+	}
+	removed, errs := 0, []error{}
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || keep(pod.Annotations[AnnotationBuild], pod.Labels[LabelInstance]) {
+			continue
+		}
+		if err := client.clientset.CoreV1().Pods(namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+
+	secrets, err := client.clientset.CoreV1().Secrets(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: LabelManaged + "=true",
+	})
+	if err != nil {
+		return removed, errors.Join(append(errs, err)...)
+	}
+	for _, secret := range secrets.Items {
+		if keep(secret.Annotations[AnnotationBuild], secret.Labels[LabelInstance]) {
+			continue
+		}
+		if err := client.clientset.CoreV1().Secrets(namespace).Delete(context.Background(), secret.Name, metav1.DeleteOptions{}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+
+	claims, err := client.clientset.CoreV1().PersistentVolumeClaims(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: LabelManaged + "=true",
+	})
+	if err != nil {
+		return removed, errors.Join(append(errs, err)...)
+	}
+	for _, claim := range claims.Items {
+		if claim.DeletionTimestamp != nil || keep(claim.Annotations[AnnotationBuild], claim.Labels[LabelInstance]) {
+			continue
+		}
+		if err := client.clientset.CoreV1().PersistentVolumeClaims(namespace).Delete(context.Background(), claim.Name, metav1.DeleteOptions{}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+	return removed, errors.Join(errs...)
+}
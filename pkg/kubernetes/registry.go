@@ -0,0 +1,65 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/gg-mike/ccli/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Registry holds credentials for pulling images of the build, they are put
+// into image pull secret living as long as the pod.
+type Registry struct {
+	Server   string
+	Username string
+	Password string
+}
+
+var pullPolicies = map[string]corev1.PullPolicy{
+	model.PullAlways:       corev1.PullAlways,
+	model.PullIfNotPresent: corev1.PullIfNotPresent,
+	model.PullNever:        corev1.PullNever,
+}
+
+func (client Client) createRegistrySecret(namespace, name string, owner Owner, registry Registry) error {
+	config, err := json.Marshal(map[string]any{
+		"auths": map[string]any{
+			registry.Server: map[string]string{
+				"username": registry.Username,
+				"password": registry.Password,
+				"auth":     base64.StdEncoding.EncodeToString([]byte(registry.Username + ":" + registry.Password)),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: config},
+	}
+	owner.apply(&secret.ObjectMeta)
+	_, err = client.clientset.CoreV1().Secrets(namespace).Create(context.Background(), secret, metav1.CreateOptions{})
+	return err
+}
+
+// adoptSecret makes the pod owner of the secret so that the secret is garbage
+// collected together with the pod.
+func (client Client) adoptSecret(namespace, name string, pod *corev1.Pod) error {
+	secret, err := client.clientset.CoreV1().Secrets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	secret.OwnerReferences = append(secret.OwnerReferences, metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Name:       pod.Name,
+		UID:        pod.UID,
+	})
+	_, err = client.clientset.CoreV1().Secrets(namespace).Update(context.Background(), secret, metav1.UpdateOptions{})
+	return err
+}
@@ -3,18 +3,21 @@ package kubernetes
 import (
 	"bufio"
 	"context"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gg-mike/ccli/pkg/model"
 	"github.com/gg-mike/ccli/pkg/runner"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/remotecommand"
 )
 
-func (client Client) createPod(namespace, name string, config model.PipelineConfig) error {
+func (client Client) createPod(namespace, name string, owner Owner, config model.PipelineConfig, registry Registry, report func(string)) (*workspace, error) {
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: name,
@@ -22,7 +25,7 @@ func (client Client) createPod(namespace, name string, config model.PipelineConf
 		Spec: corev1.PodSpec{
 			Containers: []corev1.Container{
 				{
-					Name:  "worker",
+					Name:  model.KubernetesWorkerContainer,
 					Image: config.Image,
 					Stdin: true,
 					TTY:   true,
@@ -34,49 +37,207 @@ func (client Client) createPod(namespace, name string, config model.PipelineConf
 		},
 	}
 
-	if _, err := client.clientset.CoreV1().Pods(namespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
-		return err
+	applyRunsOn(&pod.Spec, config.RunsOn)
+	pod.Spec.Containers[0].Resources = resourceRequirements(config.Resources)
+	applyTemplate(pod, config.Kubernetes)
+	owner.apply(&pod.ObjectMeta)
+	timeout := applyServices(&pod.Spec, config.Services)
+	if policy, ok := pullPolicies[config.PullPolicy]; ok {
+		for i := range pod.Spec.Containers {
+			pod.Spec.Containers[i].ImagePullPolicy = policy
+		}
 	}
-	if err := wait.PollUntilContextTimeout(context.TODO(), 1*time.Second, 5*time.Minute, true, func(ctx context.Context) (done bool, err error) {
-		pod, err := client.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
-		if err != nil {
-			return false, err
+
+	_workspace, err := client.ensureWorkspace(namespace, name+"-workspace", owner, config.Workspace, pod, report)
+	if err != nil {
+		return nil, err
+	}
+
+	secretName := ""
+	if registry.Server != "" {
+		secretName = name + "-registry"
+		if err := client.createRegistrySecret(namespace, secretName, owner, registry); err != nil {
+			return nil, err
 		}
+		pod.Spec.ImagePullSecrets = append(pod.Spec.ImagePullSecrets, corev1.LocalObjectReference{Name: secretName})
+	}
 
-		if pod.Status.Phase != corev1.PodRunning {
-			return false, nil
+	created, err := client.clientset.CoreV1().Pods(namespace).Create(context.Background(), pod, metav1.CreateOptions{})
+	if err != nil {
+		if secretName != "" {
+			client.clientset.CoreV1().Secrets(namespace).Delete(context.Background(), secretName, metav1.DeleteOptions{})
+		}
+		return nil, err
+	}
+	if secretName != "" {
+		if err := client.adoptSecret(namespace, secretName, created); err != nil {
+			report("could not attach registry secret to pod: " + err.Error())
 		}
+	}
+	if err := client.waitForPod(namespace, name, timeout, report); err != nil {
+		if err := client.clientset.CoreV1().Pods(namespace).Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+			report("could not delete pod: " + err.Error())
+		}
+		return nil, err
+	}
+
+	return _workspace, nil
+}
 
-		return true, nil
-	}); err != nil {
-		return err
+// applyRunsOn translates the worker label selector into node selection, labels
+// of the workers correspond to labels of the cluster nodes.
+func applyRunsOn(spec *corev1.PodSpec, runsOn model.LabelSelector) {
+	if len(runsOn.MatchLabels) != 0 {
+		spec.NodeSelector = map[string]string{}
+		for key, value := range runsOn.MatchLabels {
+			spec.NodeSelector[key] = value
+		}
 	}
 
-	return nil
+	if len(runsOn.MatchExpressions) == 0 {
+		return
+	}
+	requirements := []corev1.NodeSelectorRequirement{}
+	for _, req := range runsOn.MatchExpressions {
+		operator := corev1.NodeSelectorOpIn
+		if req.Operator == model.LabelOpNotIn {
+			operator = corev1.NodeSelectorOpNotIn
+		}
+		requirements = append(requirements, corev1.NodeSelectorRequirement{
+			Key:      req.Key,
+			Operator: operator,
+			Values:   req.Values,
+		})
+	}
+	spec.Affinity = &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: requirements}},
+			},
+		},
+	}
 }
 
-func (client Client) NewRunner(namespace, name string, config model.PipelineConfig) (*runner.Runner, error) {
-	if err := client.createPod(namespace, name, config); err != nil {
-		return &runner.Runner{}, err
+// applyServices adds the services as containers of the pod, reachable from
+// the build under their names, and returns how long to wait for the pod.
+func applyServices(spec *corev1.PodSpec, services []model.ServiceConfig) time.Duration {
+	timeout := 5 * time.Minute
+	hostnames := []string{}
+	for _, service := range services {
+		container := corev1.Container{
+			Name:  service.Name,
+			Image: service.Image,
+			Args:  service.Command,
+		}
+		for _, env := range service.EnvList() {
+			key, value, _ := strings.Cut(env, "=")
+			container.Env = append(container.Env, corev1.EnvVar{Name: key, Value: value})
+		}
+		// Validated when the pipeline was saved.
+		interval, serviceTimeout, _ := service.HealthCheck.Durations()
+		if service.HealthCheck.Command != "" {
+			container.ReadinessProbe = &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					Exec: &corev1.ExecAction{Command: []string{"sh", "-c", service.HealthCheck.Command}},
+				},
+				PeriodSeconds: int32(max(interval/time.Second, 1)),
+			}
+		}
+		timeout = max(timeout, serviceTimeout)
+		spec.Containers = append(spec.Containers, container)
+		hostnames = append(hostnames, service.Name)
 	}
+	if len(hostnames) != 0 {
+		// Containers of the pod share network namespace.
+		spec.HostAliases = append(spec.HostAliases, corev1.HostAlias{IP: "127.0.0.1", Hostnames: hostnames})
+	}
+	return timeout
+}
 
-	stdinReader, stdinWriter, err := os.Pipe()
+// applyTemplate merges the pipeline's pod customization into the generated
+// pod, entries of the template win over generated ones.
+func applyTemplate(pod *corev1.Pod, template model.KubernetesConfig) {
+	pod.Labels = mergeMaps(pod.Labels, template.Labels)
+	pod.Annotations = mergeMaps(pod.Annotations, template.Annotations)
+
+	spec := &pod.Spec
+	if template.ServiceAccount != "" {
+		spec.ServiceAccountName = template.ServiceAccount
+	}
+	spec.NodeSelector = mergeMaps(spec.NodeSelector, template.NodeSelector)
+	spec.Tolerations = append(spec.Tolerations, template.Tolerations...)
+	for _, name := range template.ImagePullSecrets {
+		spec.ImagePullSecrets = append(spec.ImagePullSecrets, corev1.LocalObjectReference{Name: name})
+	}
+	spec.Volumes = append(spec.Volumes, template.Volumes...)
+	spec.InitContainers = append(spec.InitContainers, template.InitContainers...)
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == model.KubernetesWorkerContainer {
+			spec.Containers[i].VolumeMounts = append(spec.Containers[i].VolumeMounts, template.VolumeMounts...)
+		}
+	}
+}
+
+func mergeMaps(dst, src map[string]string) map[string]string {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = map[string]string{}
+	}
+	for key, value := range src {
+		dst[key] = value
+	}
+	return dst
+}
+
+func resourceRequirements(config model.ResourceConfig) corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: resourceList(config.Requests),
+		Limits:   resourceList(config.Limits),
+	}
+}
+
+func resourceList(list model.ResourceList) corev1.ResourceList {
+	out := corev1.ResourceList{}
+	for name, value := range map[corev1.ResourceName]string{
+		corev1.ResourceCPU:              list.CPU,
+		corev1.ResourceMemory:           list.Memory,
+		corev1.ResourceEphemeralStorage: list.EphemeralStorage,
+	} {
+		if q, err := resource.ParseQuantity(value); err == nil {
+			out[name] = q
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// NewRunner creates pod for the build and attaches to its shell, progress of
+// the pod start is passed to report.
+func (client Client) NewRunner(namespace, name string, owner Owner, config model.PipelineConfig, registry Registry, report func(string)) (*runner.Runner, error) {
+	_workspace, err := client.createPod(namespace, name, owner, config, registry, report)
 	if err != nil {
 		return &runner.Runner{}, err
 	}
 
-	stdoutReader, stdoutWriter, err := os.Pipe()
+	stdinReader, stdinWriter, err := os.Pipe()
 	if err != nil {
 		return &runner.Runner{}, err
 	}
 
+	// Closed with the stream error so that the runner sees why output ended.
+	stdoutReader, stdoutWriter := io.Pipe()
+
 	req := client.clientset.CoreV1().RESTClient().
 		Post().
 		Namespace(namespace).
 		Resource("pods").
 		Name(name).
 		SubResource("exec").
-		Param("container", "worker").
+		Param("container", model.KubernetesWorkerContainer).
 		Param("stdin", "true").
 		Param("stdout", "true").
 		Param("stderr", "false").
@@ -89,18 +250,30 @@ func (client Client) NewRunner(namespace, name string, config model.PipelineConf
 	}
 
 	go func() {
-		exec.StreamWithContext(context.Background(), remotecommand.StreamOptions{
+		err := exec.StreamWithContext(context.Background(), remotecommand.StreamOptions{
 			Stdin:  stdinReader,
 			Stdout: stdoutWriter,
 			Stderr: nil,
 			Tty:    false,
 		})
+		if err != nil {
+			err = fmt.Errorf("%w: %v", ErrExecTerminated, err)
+		} else {
+			err = ErrExecTerminated
+		}
+		stdoutWriter.CloseWithError(err)
 	}()
 
 	_runner := runner.NewRunner(bufio.NewWriter(stdinWriter), bufio.NewReader(stdoutReader))
 	_runner.OnShutdown = func() error {
 		return client.clientset.CoreV1().Pods(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
 	}
+	builder := &imageBuilder{client: client, namespace: namespace, name: name, owner: owner}
+	if registry.Server != "" {
+		builder.registrySecret = name + "-registry"
+	}
+	_runner.Builder = builder
+	_runner.Workspace = _workspace
 
 	return _runner, nil
 }
@@ -0,0 +1,148 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// createReadyPod runs createPod against the fake cluster, marking the pod
+// ready until createPod returns, and returns the pod as it was created.
+func createReadyPod(t *testing.T, owner Owner, config model.PipelineConfig) *corev1.Pod {
+	t.Helper()
+	clientset := fake.NewSimpleClientset()
+	client := Client{clientset: clientset}
+	pods := clientset.CoreV1().Pods("ci")
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := client.createPod("ci", "build-1", owner, config, Registry{}, func(string) {})
+		errs <- err
+	}()
+
+	// The watch may start after the first update, so it is repeated.
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Fatal(err)
+			}
+			pod, err := pods.Get(context.Background(), "build-1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return pod
+		case <-ticker.C:
+			pod, err := pods.Get(context.Background(), "build-1", metav1.GetOptions{})
+			if err != nil {
+				continue
+			}
+			pod.Status = corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: model.KubernetesWorkerContainer, Ready: true}},
+			}
+			if _, err := pods.UpdateStatus(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for the pod")
+		}
+	}
+}
+
+func TestCreatePodAppliesTemplate(t *testing.T) {
+	toleration := corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "ci", Effect: corev1.TaintEffectNoSchedule}
+	initContainer := corev1.Container{
+		Name:         "fetch",
+		Image:        "busybox",
+		VolumeMounts: []corev1.VolumeMount{{Name: "cache", MountPath: "/cache"}},
+	}
+	owner := Owner{Instance: "server-1", BuildID: "project/pipeline/1"}
+	config := model.PipelineConfig{
+		Image:  "alpine",
+		RunsOn: model.LabelSelector{MatchLabels: model.Labels{"arch": "amd64"}},
+		Kubernetes: model.KubernetesConfig{
+			ServiceAccount:   "builder",
+			NodeSelector:     map[string]string{"disk": "ssd"},
+			Tolerations:      []corev1.Toleration{toleration},
+			ImagePullSecrets: []string{"regcred"},
+			Labels:           map[string]string{"team": "infra", LabelManaged: "false", LabelInstance: "other"},
+			Annotations:      map[string]string{"note": "template", AnnotationBuild: "forged"},
+			Volumes: []corev1.Volume{{
+				Name:         "cache",
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			}},
+			VolumeMounts:   []corev1.VolumeMount{{Name: "cache", MountPath: "/cache"}},
+			InitContainers: []corev1.Container{initContainer},
+		},
+	}
+
+	pod := createReadyPod(t, owner, config)
+	spec := pod.Spec
+
+	if spec.ServiceAccountName != "builder" {
+		t.Errorf("service account = %q, want builder", spec.ServiceAccountName)
+	}
+	if want := map[string]string{"arch": "amd64", "disk": "ssd"}; !reflect.DeepEqual(spec.NodeSelector, want) {
+		t.Errorf("node selector = %v, want %v", spec.NodeSelector, want)
+	}
+	if want := []corev1.Toleration{toleration}; !reflect.DeepEqual(spec.Tolerations, want) {
+		t.Errorf("tolerations = %v, want %v", spec.Tolerations, want)
+	}
+	if want := []corev1.LocalObjectReference{{Name: "regcred"}}; !reflect.DeepEqual(spec.ImagePullSecrets, want) {
+		t.Errorf("image pull secrets = %v, want %v", spec.ImagePullSecrets, want)
+	}
+	if want := []corev1.Container{initContainer}; !reflect.DeepEqual(spec.InitContainers, want) {
+		t.Errorf("init containers = %v, want %v", spec.InitContainers, want)
+	}
+
+	volumes := map[string]bool{}
+	for _, volume := range spec.Volumes {
+		volumes[volume.Name] = true
+	}
+	if !volumes["cache"] || !volumes[workspaceVolume] {
+		t.Errorf("volumes = %v, want cache and %s", spec.Volumes, workspaceVolume)
+	}
+	mounts := map[string]string{}
+	for _, container := range spec.Containers {
+		if container.Name == model.KubernetesWorkerContainer {
+			for _, mount := range container.VolumeMounts {
+				mounts[mount.Name] = mount.MountPath
+			}
+		}
+	}
+	if want := map[string]string{"cache": "/cache", workspaceVolume: model.WorkspacePath}; !reflect.DeepEqual(mounts, want) {
+		t.Errorf("worker mounts = %v, want %v", mounts, want)
+	}
+
+	if pod.Labels["team"] != "infra" || pod.Annotations["note"] != "template" {
+		t.Errorf("template labels or annotations missing: %v %v", pod.Labels, pod.Annotations)
+	}
+	if pod.Labels[LabelManaged] != "true" || pod.Labels[LabelInstance] != owner.Instance {
+		t.Errorf("ownership labels overridden by template: %v", pod.Labels)
+	}
+	if pod.Annotations[AnnotationBuild] != owner.BuildID {
+		t.Errorf("build annotation overridden by template: %v", pod.Annotations)
+	}
+}
+
+func TestCreatePodWithoutTemplate(t *testing.T) {
+	pod := createReadyPod(t, Owner{Instance: "server-1", BuildID: "project/pipeline/1"}, model.PipelineConfig{Image: "alpine"})
+	spec := pod.Spec
+
+	if spec.ServiceAccountName != "" || spec.NodeSelector != nil || spec.Tolerations != nil || spec.InitContainers != nil {
+		t.Errorf("unexpected pod customization: %+v", spec)
+	}
+	if len(spec.Volumes) != 1 || spec.Volumes[0].Name != workspaceVolume {
+		t.Errorf("volumes = %v, want only %s", spec.Volumes, workspaceVolume)
+	}
+}
@@ -0,0 +1,81 @@
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/gg-mike/ccli/pkg/model"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const workspaceVolume = "ccli-workspace"
+
+// workspace is persistent volume claim mounted at the work dir, it is created
+// by the first pod of the build and reused by the following ones.
+type workspace struct {
+	client    Client
+	namespace string
+	name      string
+}
+
+// ensureWorkspace creates the claim unless it exists and mounts it into the
+// worker container.
+func (client Client) ensureWorkspace(namespace, name string, owner Owner, config model.WorkspaceConfig, pod *corev1.Pod, report func(string)) (*workspace, error) {
+	claims := client.clientset.CoreV1().PersistentVolumeClaims(namespace)
+	if _, err := claims.Get(context.Background(), name, metav1.GetOptions{}); err == nil {
+		report("reusing workspace claim [" + name + "]")
+	} else if apierrors.IsNotFound(err) {
+		size := config.Size
+		if size == "" {
+			size = model.DefaultWorkspaceSize
+		}
+		// Validated when the pipeline was saved.
+		quantity, _ := resource.ParseQuantity(size)
+		claim := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: quantity},
+				},
+			},
+		}
+		if config.StorageClass != "" {
+			claim.Spec.StorageClassName = &config.StorageClass
+		}
+		owner.apply(&claim.ObjectMeta)
+		if _, err := claims.Create(context.Background(), claim, metav1.CreateOptions{}); err != nil {
+			return nil, err
+		}
+		report("created workspace claim [" + name + "]")
+	} else {
+		return nil, err
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: workspaceVolume,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: name},
+		},
+	})
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == model.KubernetesWorkerContainer {
+			pod.Spec.Containers[i].VolumeMounts = append(pod.Spec.Containers[i].VolumeMounts, corev1.VolumeMount{
+				Name:      workspaceVolume,
+				MountPath: model.WorkspacePath,
+			})
+		}
+	}
+	return &workspace{client: client, namespace: namespace, name: name}, nil
+}
+
+func (w *workspace) Path() string {
+	return model.WorkspacePath
+}
+
+// Remove deletes the claim, Kubernetes keeps it until the pod using it is gone.
+func (w *workspace) Remove() error {
+	return w.client.clientset.CoreV1().PersistentVolumeClaims(w.namespace).Delete(context.Background(), w.name, metav1.DeleteOptions{})
+}
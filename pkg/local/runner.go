@@ -0,0 +1,135 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/runner"
+)
+
+const defaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// Options of the sandbox the build shell runs in, user, root and isolation
+// are supported on Linux only and (except isolation) require root.
+//
+// Shell running as the server user can read environment of the server (e.g.
+// vault token) through /proc unless it is isolated. Network of the host is
+// shared in any case.
+type Options struct {
+	Shell string
+	// User runs the shell as another user of the host.
+	User string
+	// Root is directory the shell is chrooted into.
+	Root string
+	// Isolate runs the shell in its own mount, PID, IPC and UTS namespaces
+	// with /proc of its own, unprivileged servers get a user namespace as
+	// well. Shell of root server keeps its capabilities unless User is set.
+	Isolate bool
+	// Home is home directory of the shell, work dirs are created in it.
+	Home string
+}
+
+// NewRunner spawns the shell on the server host. The shell gets clean
+// environment so that nothing of the server (e.g. vault token) leaks into it.
+func NewRunner(options Options) (*runner.Runner, error) {
+	if options.Shell == "" {
+		options.Shell = "sh"
+	}
+	cmd := exec.Command(options.Shell)
+	home := options.Home
+	username := os.Getenv("USER")
+	if options.User != "" {
+		u, err := user.Lookup(options.User)
+		if err != nil {
+			return &runner.Runner{}, err
+		}
+		username = u.Username
+		if home == "" {
+			home = u.HomeDir
+		}
+	}
+	if home == "" {
+		home = filepath.Join(os.TempDir(), "ccli-local")
+		if options.Root == "" {
+			if err := os.MkdirAll(home, 0o700); err != nil {
+				return &runner.Runner{}, err
+			}
+		}
+	}
+	cmd.Env = []string{"PATH=" + defaultPath, "HOME=" + home, "USER=" + username, "LANG=C.UTF-8"}
+	cmd.Dir = "/"
+	if options.Root == "" {
+		cmd.Dir = home
+	}
+	if err := sandbox(cmd, options); err != nil {
+		return &runner.Runner{}, err
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return &runner.Runner{}, err
+	}
+	// Shell errors (e.g. syntax) go to the build output as well.
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return &runner.Runner{}, err
+	}
+	cmd.Stdout, cmd.Stderr = writer, writer
+	if err := cmd.Start(); err != nil {
+		reader.Close()
+		writer.Close()
+		return &runner.Runner{}, fmt.Errorf("starting local shell: %w", err)
+	}
+	writer.Close()
+
+	_runner := runner.NewRunner(stdin, reader)
+	_runner.OnShutdown = func() error {
+		defer reader.Close()
+		return shutdown(cmd, stdin)
+	}
+	return _runner, nil
+}
+
+// shutdown lets the shell exit after its stdin is closed and kills whatever
+// is left of the build afterwards.
+func shutdown(cmd *exec.Cmd, stdin io.Closer) error {
+	stdin.Close()
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		kill(cmd)
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			// Exit status of the last command, the runner reported it already.
+			return nil
+		}
+		return err
+	case <-time.After(10 * time.Second):
+		kill(cmd)
+		return <-done
+	}
+}
+
+// Probe checks that the shell can be started with the options.
+func Probe(options Options) error {
+	if options.Shell == "" {
+		options.Shell = "sh"
+	}
+	if options.Root != "" {
+		if info, err := os.Stat(options.Root); err != nil {
+			return err
+		} else if !info.IsDir() {
+			return fmt.Errorf("root [%s] is not a directory", options.Root)
+		}
+		return nil
+	}
+	_, err := exec.LookPath(options.Shell)
+	return err
+}
@@ -0,0 +1,132 @@
+//go:build linux
+
+package local
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// sandboxInit is the name the server runs itself under to set up isolated
+// sandbox before the shell replaces it.
+const sandboxInit = "ccli-sandbox-init"
+
+const (
+	capSysChroot = 18
+	capSysAdmin  = 21
+
+	prCapAmbient         = 47
+	prCapAmbientClearAll = 4
+)
+
+func init() {
+	if len(os.Args) == 0 || os.Args[0] != sandboxInit {
+		return
+	}
+	if err := initSandbox(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "sandbox: "+err.Error())
+		os.Exit(1)
+	}
+}
+
+func sandbox(cmd *exec.Cmd, options Options) error {
+	attr := &syscall.SysProcAttr{Setpgid: true}
+	var uid, gid string
+	if options.User != "" {
+		u, err := user.Lookup(options.User)
+		if err != nil {
+			return err
+		}
+		uid, gid = u.Uid, u.Gid
+	}
+	if !options.Isolate {
+		attr.Chroot = options.Root
+		if uid != "" {
+			attr.Credential = credential(uid, gid)
+		}
+		cmd.SysProcAttr = attr
+		return nil
+	}
+
+	// Root and user are set up by the init once /proc is mounted.
+	attr.Cloneflags = syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if os.Geteuid() != 0 {
+		// Unprivileged namespaces, the shell keeps identity of the server
+		// and the init gets only capabilities needed for the setup.
+		attr.Cloneflags |= syscall.CLONE_NEWUSER
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Geteuid(), HostID: os.Geteuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getegid(), HostID: os.Getegid(), Size: 1}}
+		attr.AmbientCaps = []uintptr{capSysAdmin, capSysChroot}
+	}
+	cmd.SysProcAttr = attr
+	cmd.Path = "/proc/self/exe"
+	// Shell is looked up by the init, possibly in the root.
+	cmd.Err = nil
+	cmd.Args = []string{sandboxInit, options.Root, uid, gid, cmd.Args[0]}
+	return nil
+}
+
+// initSandbox mounts /proc of the new PID namespace over the one of the
+// server, so that processes of the server and their environment are out of
+// reach, and replaces itself with the shell.
+func initSandbox(args []string) error {
+	if len(args) != 4 {
+		return fmt.Errorf("unexpected arguments %q", args)
+	}
+	root, uid, gid, shell := args[0], args[1], args[2], args[3]
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("making mounts private: %w", err)
+	}
+	if err := syscall.Mount("proc", filepath.Join("/", root, "proc"), "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mounting /proc: %w", err)
+	}
+	if root != "" {
+		if err := syscall.Chroot(root); err != nil {
+			return fmt.Errorf("chroot: %w", err)
+		}
+		if err := os.Chdir("/"); err != nil {
+			return err
+		}
+	}
+	if uid != "" {
+		c := credential(uid, gid)
+		if err := syscall.Setgroups([]int{}); err != nil {
+			return err
+		}
+		if err := syscall.Setgid(int(c.Gid)); err != nil {
+			return err
+		}
+		if err := syscall.Setuid(int(c.Uid)); err != nil {
+			return err
+		}
+	}
+	// Shell of unprivileged server runs without capabilities, it cannot
+	// unmount /proc to reach the one of the server.
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prCapAmbient, prCapAmbientClearAll, 0); errno != 0 {
+		return fmt.Errorf("dropping capabilities: %w", errno)
+	}
+	path, err := exec.LookPath(shell)
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(path, []string{shell}, os.Environ())
+}
+
+func credential(uid, gid string) *syscall.Credential {
+	u, _ := strconv.ParseUint(uid, 10, 32)
+	g, _ := strconv.ParseUint(gid, 10, 32)
+	return &syscall.Credential{Uid: uint32(u), Gid: uint32(g)}
+}
+
+// kill terminates process group of the shell, background processes of the
+// build included.
+func kill(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,35 @@
+//go:build linux
+
+package local
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIsolatedShellDoesNotSeeServer(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("namespaces are set up by root in tests")
+	}
+	_runner, err := NewRunner(Options{Isolate: true, Home: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer _runner.Shutdown()
+
+	out := []string{}
+	_runner.OnCmd, _runner.OnOut = func(string, int, int) {}, func(line string) { out = append(out, line) }
+	if err := _runner.Run([]string{
+		"echo $$",
+		"cat /proc/1/comm",
+		fmt.Sprintf("test -e /proc/%d && echo visible || echo hidden", os.Getpid()),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Environment of the server is reachable through its /proc entry only.
+	if got, want := strings.Join(out, "\n"), "1\nsh\nhidden"; got != want {
+		t.Errorf("shell sees %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package local
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func sandbox(cmd *exec.Cmd, options Options) error {
+	if options.User != "" || options.Root != "" || options.Isolate {
+		return errors.New("user, root and isolation of local workers are supported on Linux only")
+	}
+	return nil
+}
+
+func kill(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		cmd.Process.Kill()
+	}
+}
@@ -7,8 +7,7 @@ import (
 )
 
 type Flags struct {
-	DbUrl     string
-	Scheduler string
+	DbUrl string
 }
 
 type Handler struct {
@@ -28,28 +27,23 @@ func NewHandler(logger log.Logger, f *Flags) *Handler {
 }
 
 func (h *Handler) Run() error {
-	if h.flags.Scheduler == "standalone" {
-		return db.Get().AutoMigrate(
-			&model.Worker{},
-			&model.Project{},
-			&model.Pipeline{},
-			&model.Build{},
-			&model.BuildStep{},
-			&model.Secret{},
-			&model.Variable{},
-			&model.QueueElem{},
-		)
-	} else {
-		return db.Get().AutoMigrate(
-			&model.Project{},
-			&model.Pipeline{},
-			&model.Build{},
-			&model.BuildStep{},
-			&model.Secret{},
-			&model.Variable{},
-			&model.QueueElem{},
-		)
-	}
+	return db.Get().AutoMigrate(
+		&model.Worker{},
+		&model.WorkerPool{},
+		&model.AgentToken{},
+		&model.Project{},
+		&model.Pipeline{},
+		&model.Build{},
+		&model.BuildStep{},
+		&model.Secret{},
+		&model.Variable{},
+		&model.QueueElem{},
+		&model.User{},
+		&model.Team{},
+		&model.RoleBinding{},
+		&model.Token{},
+		&model.AuditEntry{},
+	)
 }
 
 func (h *Handler) initDb() {
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// AgentToken is the one-time token an agent exchanges for its worker entry
+// and credentials on registration.
+type AgentToken struct {
+	ID         uint           `json:"id"          gorm:"primaryKey"`
+	Hash       string         `json:"-"           gorm:"not null;uniqueIndex"`
+	Pool       string         `json:"pool"`
+	CreatedBy  string         `json:"created_by"`
+	WorkerName sql.NullString `json:"worker_name"`
+	ExpiresAt  sql.NullTime   `json:"expires_at"`
+	UsedAt     sql.NullTime   `json:"used_at"`
+	CreatedAt  time.Time      `json:"created_at"  gorm:"default:now()"`
+}
+
+type AgentTokenInput struct {
+	// Pool the agent is registered into, agents asking for another one are
+	// rejected. Empty lets the agent choose.
+	Pool string `json:"pool"`
+	TTL  string `json:"ttl"`
+}
+
+func (m AgentToken) Usable() bool {
+	return !m.UsedAt.Valid && !(m.ExpiresAt.Valid && m.ExpiresAt.Time.Before(time.Now()))
+}
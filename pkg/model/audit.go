@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+const AuditRedacted = "[redacted]"
+
+type AuditEntry struct {
+	ID         uint                   `json:"id"          gorm:"primaryKey"`
+	Actor      string                 `json:"actor"       gorm:"not null;index"`
+	Action     string                 `json:"action"      gorm:"not null"`
+	Resource   string                 `json:"resource"    gorm:"not null;index"`
+	ResourceID string                 `json:"resource_id" gorm:"index"`
+	Diff       map[string]AuditChange `json:"diff"        gorm:"serializer:json"`
+	CreatedAt  time.Time              `json:"created_at"  gorm:"default:now();index"`
+}
+
+type AuditChange struct {
+	Before any `json:"before,omitempty"`
+	After  any `json:"after,omitempty"`
+}
+
+func (AuditEntry) TableName() string {
+	return "audit"
+}
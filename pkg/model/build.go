@@ -21,22 +21,39 @@ const (
 )
 
 type Build struct {
-	Number       uint           `json:"number"          gorm:"primaryKey;uniqueIndex:idx_builds"`
-	PipelineName string         `json:"pipeline_name"   gorm:"primaryKey;uniqueIndex:idx_builds"`
-	ProjectName  string         `json:"project_name"    gorm:"primaryKey;uniqueIndex:idx_builds"`
-	Status       string         `json:"status"          gorm:"default:scheduled"`
-	Steps        []BuildStep    `json:"steps,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:BuildNumber,PipelineName,ProjectName"`
-	WorkerName   sql.NullString `json:"worker_name"`
-	CreatedAt    time.Time      `json:"created_at"      gorm:"default:now()"`
-	UpdatedAt    time.Time      `json:"updated_at"      gorm:"default:now()"`
+	Number           uint           `json:"number"            gorm:"primaryKey;uniqueIndex:idx_builds"`
+	PipelineName     string         `json:"pipeline_name"     gorm:"primaryKey;uniqueIndex:idx_builds"`
+	ProjectName      string         `json:"project_name"      gorm:"primaryKey;uniqueIndex:idx_builds"`
+	Status           string         `json:"status"            gorm:"default:scheduled"`
+	Priority         int            `json:"priority"          gorm:"default:0"`
+	ConcurrencyGroup string         `json:"concurrency_group" gorm:"index"`
+	StatusReason     string         `json:"status_reason"`
+	Steps            []BuildStep    `json:"steps,omitempty"   gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:BuildNumber,PipelineName,ProjectName"`
+	WorkerName       sql.NullString `json:"worker_name"`
+	Instance         string         `json:"instance"`
+	Requested        ResourceUnits  `json:"requested"         gorm:"embedded;embeddedPrefix:requested_"`
+	Images           []BuiltImage   `json:"images,omitempty"  gorm:"serializer:json"`
+	CreatedAt        time.Time      `json:"created_at"        gorm:"default:now()"`
+	UpdatedAt        time.Time      `json:"updated_at"        gorm:"default:now()"`
+
+	// canceled builds are finished once the transaction commits.
+	canceled []string
 }
 
 type BuildShort struct {
-	Number     uint           `json:"number"`
-	Status     string         `json:"status"`
-	WorkerName sql.NullString `json:"worker_name,omitempty"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
+	Number           uint           `json:"number"`
+	Status           string         `json:"status"`
+	Priority         int            `json:"priority"`
+	ConcurrencyGroup string         `json:"concurrency_group"`
+	StatusReason     string         `json:"status_reason"`
+	WorkerName       sql.NullString `json:"worker_name,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+}
+
+type BuildInput struct {
+	// Priority overrides the one configured for the pipeline, higher goes first.
+	Priority *int `json:"priority"`
 }
 
 func (m *Build) BeforeCreate(tx *gorm.DB) error {
@@ -49,9 +66,71 @@ func (m *Build) BeforeCreate(tx *gorm.DB) error {
 		}
 	}
 	m.Number = result + 1
+
+	var pipeline Pipeline
+	if err := tx.Session(&gorm.Session{NewDB: true}).First(&pipeline, &Pipeline{Name: m.PipelineName, ProjectName: m.ProjectName}).Error; err != nil {
+		return err
+	}
+	if input, ok := tx.InstanceGet("input"); !ok || input.(BuildInput).Priority == nil {
+		m.Priority = pipeline.Config.Priority
+	}
+
+	concurrency := pipeline.Config.Concurrency
+	if concurrency.Group == "" {
+		return nil
+	}
+	m.ConcurrencyGroup = concurrency.GroupFor(m.ProjectName, m.PipelineName, pipeline.Branch)
+	return m.supersede(tx.Session(&gorm.Session{NewDB: true}), concurrency.CancelInProgress)
+}
+
+// supersede cancels older builds of the same concurrency group that are still
+// waiting, along with running ones if requested.
+func (m *Build) supersede(tx *gorm.DB, cancelInProgress bool) error {
+	statuses := []string{BuildScheduled}
+	if cancelInProgress {
+		statuses = append(statuses, BuildRunning)
+	}
+	var older []Build
+	if err := tx.Where("concurrency_group = ? AND status IN ?", m.ConcurrencyGroup, statuses).Find(&older).Error; err != nil {
+		return err
+	}
+	for _, build := range older {
+		canceled, err := build.Cancel(tx, "superseded by build "+m.ID())
+		if err != nil {
+			return err
+		}
+		if canceled {
+			m.canceled = append(m.canceled, build.ID())
+		}
+	}
 	return nil
 }
 
+// Cancel stops the build with the reason and removes it from the queue if it
+// still waits there. Build which ended meanwhile is left as it is, so that it
+// is unbound only once. Canceled build has to be finished by the scheduler
+// once tx commits.
+func (m Build) Cancel(tx *gorm.DB, reason string) (bool, error) {
+	result := tx.Session(&gorm.Session{SkipHooks: true}).Model(&m).
+		Where("status IN ?", []string{BuildScheduled, BuildRunning}).
+		Updates(map[string]any{
+			"status":        BuildCanceled,
+			"status_reason": reason,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	return true, tx.Delete(&QueueElem{ID: m.ID()}).Error
+}
+
+// AfterCommit finishes builds canceled by the committed create or update.
+func (m *Build) AfterCommit() {
+	for _, buildID := range m.canceled {
+		go scheduler.Get().Finished(buildID)
+	}
+	m.canceled = nil
+}
+
 func (m *Build) AfterCreate(tx *gorm.DB) error {
 	go scheduler.Get().Schedule(m.ID())
 	return nil
@@ -65,7 +144,7 @@ func (m *Build) BeforeUpdate(tx *gorm.DB) error {
 	switch prev.(Build).Status {
 	case BuildScheduled, BuildRunning:
 		tx.Statement.SetColumn("status", BuildCanceled)
-		go scheduler.Get().Finished(m.ID())
+		m.canceled = append(m.canceled, m.ID())
 		return nil
 	default:
 		return fmt.Errorf("cannot change status of build from [%s] to [%s]",
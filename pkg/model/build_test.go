@@ -0,0 +1,45 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gg-mike/ccli/pkg/scheduler"
+)
+
+// testScheduler records finished builds.
+type testScheduler struct {
+	done chan string
+}
+
+func (s *testScheduler) Schedule(string) {}
+
+func (s *testScheduler) Finished(buildID string) { s.done <- buildID }
+
+func (s *testScheduler) ChangeInWorkers() {}
+
+func TestBuildCanceledFinishedAfterCommit(t *testing.T) {
+	s := &testScheduler{done: make(chan string, 1)}
+	scheduler.Init(s)
+
+	build := Build{ProjectName: "p", PipelineName: "x", Number: 1}
+	tx := updateTx(Build{ProjectName: "p", PipelineName: "x", Number: 1, Status: BuildRunning})
+	columns := map[string]any{}
+	tx.Statement.Dest = columns
+	if err := build.BeforeUpdate(tx); err != nil {
+		t.Fatal(err)
+	}
+	if columns["status"] != BuildCanceled {
+		t.Errorf("status set to %v, want %s", columns["status"], BuildCanceled)
+	}
+	select {
+	case buildID := <-s.done:
+		t.Fatalf("build %s finished before commit", buildID)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	build.AfterCommit()
+	if buildID := <-s.done; buildID != "p/x/1" {
+		t.Errorf("finished build %s, want p/x/1", buildID)
+	}
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/gg-mike/ccli/pkg/vault"
+)
+
+// Targets of pipelines, builds without target run on any worker matching
+// their configuration.
+const (
+	TargetStandalone = "standalone"
+	TargetKubernetes = "kubernetes"
+)
+
+var Targets = []string{TargetStandalone, TargetKubernetes}
+
+// ClusterConfig connects Kubernetes worker to the cluster, builds run as pods
+// in the namespace.
+type ClusterConfig struct {
+	// Mode is inner (cluster the server runs in) or outer (from kubeconfig
+	// kept in vault).
+	Mode         string `json:"mode"`
+	Namespace    string `json:"namespace"`
+	BuilderImage string `json:"builder_image"`
+}
+
+func (c ClusterConfig) Validate() error {
+	if !slices.Contains([]string{"inner", "outer"}, c.Mode) {
+		return fmt.Errorf("unknown cluster mode [%s] (expected inner or outer)", c.Mode)
+	}
+	if c.Namespace == "" {
+		return errors.New("missing cluster namespace")
+	}
+	return nil
+}
+
+func ValidateTarget(target string) error {
+	if target != "" && !slices.Contains(Targets, target) {
+		return fmt.Errorf("unknown target [%s] (expected one of %v)", target, Targets)
+	}
+	return nil
+}
+
+// Serves tells whether builds of the target may run on the worker.
+func (m Worker) Serves(target string) bool {
+	switch target {
+	case TargetKubernetes:
+		return m.ExecutorType() == WorkerKubernetes
+	case TargetStandalone:
+		return m.ExecutorType() != WorkerKubernetes
+	}
+	return true
+}
+
+// Kubeconfig returns kubeconfig of the outer cluster of the worker.
+func (m Worker) Kubeconfig() (string, error) {
+	if m.ExecutorType() != WorkerKubernetes || m.Cluster.Mode != "outer" {
+		return "", errors.New("worker does not have a kubeconfig")
+	}
+	return vault.GetStr(m.Name)
+}
@@ -0,0 +1,8 @@
+package model
+
+// Committer is implemented by models notifying other components (e.g. the
+// scheduler) about their change, which may only happen once the transaction
+// of the change commits.
+type Committer interface {
+	AfterCommit()
+}
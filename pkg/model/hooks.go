@@ -0,0 +1,37 @@
+package model
+
+import "gorm.io/gorm"
+
+// Hooks of workers reach the host of the worker, e.g. to check it or keep
+// its credentials, as the worker is saved or deleted. They are implemented by
+// executors of worker types, each one is optional. Input is nil when the
+// worker is not saved through the API.
+type (
+	WorkerCreator interface {
+		// CreateWorker is called before the worker is inserted.
+		CreateWorker(tx *gorm.DB, worker *Worker, input *WorkerInput) error
+	}
+	WorkerSecrets interface {
+		// StoreSecrets is called once the worker is inserted, secrets of the
+		// input are kept out of the database.
+		StoreSecrets(worker Worker, input *WorkerInput) error
+	}
+	WorkerUpdater interface {
+		// UpdateWorker is called after the worker is updated, previous one
+		// of other type was removed first.
+		UpdateWorker(tx *gorm.DB, prev Worker, worker *Worker, input *WorkerInput) error
+	}
+	WorkerRemover interface {
+		// RemoveWorker is called after the worker is deleted or changed to
+		// other type.
+		RemoveWorker(worker Worker) error
+	}
+)
+
+var workerHooks = func(workerType string) any { return nil }
+
+// SetWorkerHooks makes hooks of workers looked up by their type, executor
+// package sets its registry.
+func SetWorkerHooks(lookup func(workerType string) any) {
+	workerHooks = lookup
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ImageBuildConfig turns the step into building an image from Dockerfile in
+// the work dir. Tags may use ${project}, ${pipeline}, ${build} and ${branch}.
+type ImageBuildConfig struct {
+	Image      string            `json:"image"`
+	Context    string            `json:"context"`
+	Dockerfile string            `json:"dockerfile"`
+	Tags       []string          `json:"tags"`
+	BuildArgs  map[string]string `json:"build_args"`
+	Push       bool              `json:"push"`
+}
+
+// BuiltImage is an image produced by the build.
+type BuiltImage struct {
+	Step   string   `json:"step"`
+	Tags   []string `json:"tags"`
+	Digest string   `json:"digest"`
+	Pushed bool     `json:"pushed"`
+}
+
+func (c ImageBuildConfig) Validate() error {
+	if c.Image == "" {
+		return errors.New("image_build: missing image")
+	}
+	for _, path := range []string{c.Context, c.Dockerfile} {
+		if strings.ContainsAny(path, "'\n") {
+			return fmt.Errorf("image_build: invalid path [%s]", path)
+		}
+	}
+	return nil
+}
+
+func validateSteps(steps []PipelineConfigStep) error {
+	for _, step := range steps {
+		if step.ImageBuild == nil {
+			continue
+		}
+		if len(step.Commands) != 0 {
+			return fmt.Errorf("step [%s]: image_build step cannot have commands", step.Name)
+		}
+		if err := step.ImageBuild.Validate(); err != nil {
+			return fmt.Errorf("step [%s]: %v", step.Name, err)
+		}
+	}
+	return nil
+}
+
+// ContextDir returns build context relative to the work dir.
+func (c ImageBuildConfig) ContextDir() string {
+	if c.Context == "" {
+		return "."
+	}
+	return c.Context
+}
+
+// DockerfilePath returns path of Dockerfile relative to the build context.
+func (c ImageBuildConfig) DockerfilePath() string {
+	if c.Dockerfile == "" {
+		return "Dockerfile"
+	}
+	return c.Dockerfile
+}
+
+// References returns full image references for the build, tagged with the
+// build number when no tags are configured.
+func (c ImageBuildConfig) References(build Build, branch string) []string {
+	tags := c.Tags
+	if len(tags) == 0 {
+		tags = []string{"build-${build}"}
+	}
+	replacer := strings.NewReplacer(
+		"${project}", build.ProjectName,
+		"${pipeline}", build.PipelineName,
+		"${build}", strconv.FormatUint(uint64(build.Number), 10),
+		"${branch}", strings.ReplaceAll(branch, "/", "-"),
+	)
+	refs := []string{}
+	for _, tag := range tags {
+		refs = append(refs, c.Image+":"+replacer.Replace(tag))
+	}
+	return refs
+}
+
+// ImageLabels returns labels describing where the image comes from.
+func ImageLabels(build Build, repo, branch string) map[string]string {
+	return map[string]string{
+		"org.opencontainers.image.source": repo,
+		"ccli.project":                    build.ProjectName,
+		"ccli.pipeline":                   build.PipelineName,
+		"ccli.build_number":               strconv.FormatUint(uint64(build.Number), 10),
+		"ccli.branch":                     branch,
+	}
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Name of the container running the build steps in the pod.
+const KubernetesWorkerContainer = "worker"
+
+// KubernetesConfig is merged into the pod created for the build when builds
+// are scheduled on Kubernetes, it is ignored by the standalone scheduler.
+type KubernetesConfig struct {
+	ServiceAccount   string               `json:"service_account"`
+	NodeSelector     map[string]string    `json:"node_selector"`
+	Tolerations      []corev1.Toleration  `json:"tolerations"`
+	ImagePullSecrets []string             `json:"image_pull_secrets"`
+	Labels           map[string]string    `json:"labels"`
+	Annotations      map[string]string    `json:"annotations"`
+	Volumes          []corev1.Volume      `json:"volumes"`
+	VolumeMounts     []corev1.VolumeMount `json:"volume_mounts"`
+	InitContainers   []corev1.Container   `json:"init_containers"`
+}
+
+func (c KubernetesConfig) Validate() error {
+	if c.ServiceAccount != "" {
+		if errs := validation.IsDNS1123Subdomain(c.ServiceAccount); len(errs) != 0 {
+			return fmt.Errorf("k8s.service_account: %s", strings.Join(errs, ", "))
+		}
+	}
+	for _, name := range c.ImagePullSecrets {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+			return fmt.Errorf("k8s.image_pull_secrets [%s]: %s", name, strings.Join(errs, ", "))
+		}
+	}
+	for key, value := range c.NodeSelector {
+		if err := validateLabel(key, value); err != nil {
+			return fmt.Errorf("k8s.node_selector: %v", err)
+		}
+	}
+	for key, value := range c.Labels {
+		if err := validateLabel(key, value); err != nil {
+			return fmt.Errorf("k8s.labels: %v", err)
+		}
+	}
+	for key := range c.Annotations {
+		if errs := validation.IsQualifiedName(key); len(errs) != 0 {
+			return fmt.Errorf("k8s.annotations [%s]: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	for _, toleration := range c.Tolerations {
+		switch toleration.Operator {
+		case "", corev1.TolerationOpEqual:
+		case corev1.TolerationOpExists:
+			if toleration.Value != "" {
+				return fmt.Errorf("k8s.tolerations [%s]: value must be empty with operator Exists", toleration.Key)
+			}
+		default:
+			return fmt.Errorf("k8s.tolerations [%s]: unknown operator [%s]", toleration.Key, toleration.Operator)
+		}
+	}
+
+	volumes := map[string]bool{}
+	for _, volume := range c.Volumes {
+		if errs := validation.IsDNS1123Label(volume.Name); len(errs) != 0 {
+			return fmt.Errorf("k8s.volumes [%s]: %s", volume.Name, strings.Join(errs, ", "))
+		}
+		if volumes[volume.Name] {
+			return fmt.Errorf("k8s.volumes [%s]: duplicate name", volume.Name)
+		}
+		volumes[volume.Name] = true
+	}
+	mounts := append([]corev1.VolumeMount{}, c.VolumeMounts...)
+	containers := map[string]bool{KubernetesWorkerContainer: true}
+	for _, container := range c.InitContainers {
+		if errs := validation.IsDNS1123Label(container.Name); len(errs) != 0 {
+			return fmt.Errorf("k8s.init_containers [%s]: %s", container.Name, strings.Join(errs, ", "))
+		}
+		if containers[container.Name] {
+			return fmt.Errorf("k8s.init_containers [%s]: duplicate name", container.Name)
+		}
+		containers[container.Name] = true
+		if container.Image == "" {
+			return fmt.Errorf("k8s.init_containers [%s]: missing image", container.Name)
+		}
+		mounts = append(mounts, container.VolumeMounts...)
+	}
+	for _, mount := range mounts {
+		if !volumes[mount.Name] {
+			return fmt.Errorf("k8s.volume_mounts [%s]: no such volume", mount.Name)
+		}
+		if mount.MountPath == "" {
+			return fmt.Errorf("k8s.volume_mounts [%s]: missing mount path", mount.Name)
+		}
+	}
+	return nil
+}
+
+func validateLabel(key, value string) error {
+	if errs := validation.IsQualifiedName(key); len(errs) != 0 {
+		return fmt.Errorf("key [%s]: %s", key, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsValidLabelValue(value); len(errs) != 0 {
+		return fmt.Errorf("value [%s]: %s", value, strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestKubernetesConfigValidate(t *testing.T) {
+	cache := corev1.Volume{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+	tests := []struct {
+		name   string
+		config KubernetesConfig
+		err    string
+	}{
+		{"empty", KubernetesConfig{}, ""},
+		{"full", KubernetesConfig{
+			ServiceAccount:   "builder",
+			NodeSelector:     map[string]string{"kubernetes.io/arch": "amd64"},
+			Tolerations:      []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+			ImagePullSecrets: []string{"regcred"},
+			Labels:           map[string]string{"team": "infra"},
+			Annotations:      map[string]string{"example.com/note": "any value"},
+			Volumes:          []corev1.Volume{cache},
+			VolumeMounts:     []corev1.VolumeMount{{Name: "cache", MountPath: "/cache"}},
+			InitContainers: []corev1.Container{{
+				Name:         "fetch",
+				Image:        "busybox",
+				VolumeMounts: []corev1.VolumeMount{{Name: "cache", MountPath: "/cache"}},
+			}},
+		}, ""},
+		{"invalid service account", KubernetesConfig{ServiceAccount: "Builder_1"}, "k8s.service_account"},
+		{"invalid pull secret", KubernetesConfig{ImagePullSecrets: []string{"reg cred"}}, "k8s.image_pull_secrets [reg cred]"},
+		{"invalid node selector key", KubernetesConfig{NodeSelector: map[string]string{"-disk": "ssd"}}, "k8s.node_selector: key [-disk]"},
+		{"invalid label value", KubernetesConfig{Labels: map[string]string{"team": "in fra"}}, "k8s.labels: value [in fra]"},
+		{"invalid annotation key", KubernetesConfig{Annotations: map[string]string{"a/b/c": ""}}, "k8s.annotations [a/b/c]"},
+		{"toleration exists with value", KubernetesConfig{Tolerations: []corev1.Toleration{{Key: "k", Operator: corev1.TolerationOpExists, Value: "v"}}}, "value must be empty"},
+		{"unknown toleration operator", KubernetesConfig{Tolerations: []corev1.Toleration{{Key: "k", Operator: "Gt"}}}, "unknown operator [Gt]"},
+		{"invalid volume name", KubernetesConfig{Volumes: []corev1.Volume{{Name: "Cache"}}}, "k8s.volumes [Cache]"},
+		{"duplicate volume", KubernetesConfig{Volumes: []corev1.Volume{cache, cache}}, "k8s.volumes [cache]: duplicate name"},
+		{"mount without volume", KubernetesConfig{VolumeMounts: []corev1.VolumeMount{{Name: "cache", MountPath: "/cache"}}}, "k8s.volume_mounts [cache]: no such volume"},
+		{"mount without path", KubernetesConfig{Volumes: []corev1.Volume{cache}, VolumeMounts: []corev1.VolumeMount{{Name: "cache"}}}, "missing mount path"},
+		{"init container named worker", KubernetesConfig{InitContainers: []corev1.Container{{Name: KubernetesWorkerContainer, Image: "busybox"}}}, "duplicate name"},
+		{"duplicate init container", KubernetesConfig{InitContainers: []corev1.Container{{Name: "a", Image: "busybox"}, {Name: "a", Image: "busybox"}}}, "k8s.init_containers [a]: duplicate name"},
+		{"init container without image", KubernetesConfig{InitContainers: []corev1.Container{{Name: "fetch"}}}, "missing image"},
+		{"init container mount without volume", KubernetesConfig{InitContainers: []corev1.Container{{
+			Name:         "fetch",
+			Image:        "busybox",
+			VolumeMounts: []corev1.VolumeMount{{Name: "cache", MountPath: "/cache"}},
+		}}}, "no such volume"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("Validate() error = %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("Validate() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+)
+
+const (
+	LabelOpIn    = "in"
+	LabelOpNotIn = "not in"
+)
+
+type Labels map[string]string
+
+// LabelSelector chooses workers by their labels, all conditions must hold.
+type LabelSelector struct {
+	MatchLabels      Labels             `json:"match_labels,omitempty"`
+	MatchExpressions []LabelRequirement `json:"match_expressions,omitempty"`
+}
+
+type LabelRequirement struct {
+	Key      string   `json:"key"`
+	Operator string   `json:"operator"`
+	Values   []string `json:"values"`
+}
+
+func (s LabelSelector) Empty() bool {
+	return len(s.MatchLabels) == 0 && len(s.MatchExpressions) == 0
+}
+
+func (s LabelSelector) Matches(labels Labels) bool {
+	for key, value := range s.MatchLabels {
+		if actual, ok := labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	for _, req := range s.MatchExpressions {
+		actual, ok := labels[req.Key]
+		switch req.Operator {
+		case LabelOpIn:
+			if !ok || !slices.Contains(req.Values, actual) {
+				return false
+			}
+		case LabelOpNotIn:
+			if ok && slices.Contains(req.Values, actual) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (s LabelSelector) Validate() error {
+	for _, req := range s.MatchExpressions {
+		if req.Key == "" {
+			return fmt.Errorf("label requirement without key")
+		}
+		if req.Operator != LabelOpIn && req.Operator != LabelOpNotIn {
+			return fmt.Errorf("unknown operator [%s] for label [%s] (expected '%s' or '%s')",
+				req.Operator, req.Key, LabelOpIn, LabelOpNotIn)
+		}
+		if len(req.Values) == 0 {
+			return fmt.Errorf("label requirement [%s] without values", req.Key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package model
+
+// LocalConfig sets up sandbox of local workers running builds in a shell on
+// the server host.
+type LocalConfig struct {
+	Shell   string `json:"shell"`
+	User    string `json:"user"`
+	Root    string `json:"root"`
+	Isolate bool   `json:"isolate"`
+	Home    string `json:"home"`
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -34,17 +35,87 @@ type PipelineInput struct {
 }
 
 type PipelineConfig struct {
-	System     string               `json:"system"`
-	Image      string               `json:"image"`
-	Shell      string               `json:"shell"`
-	Privileged bool                 `json:"privileged"`
-	Steps      []PipelineConfigStep `json:"steps"`
-	Cleanup    []string             `json:"cleanup"`
+	System        string               `json:"system"`
+	Target        string               `json:"target"`
+	Image         string               `json:"image"`
+	PullPolicy    string               `json:"pull_policy"`
+	Registry      RegistryConfig       `json:"registry"`
+	Shell         string               `json:"shell"`
+	Privileged    bool                 `json:"privileged"`
+	RunsOn        LabelSelector        `json:"runs_on"`
+	Priority      int                  `json:"priority"`
+	MaxConcurrent int                  `json:"max_concurrent"`
+	Concurrency   ConcurrencyConfig    `json:"concurrency"`
+	Resources     ResourceConfig       `json:"resources"`
+	Kubernetes    KubernetesConfig     `json:"k8s"`
+	Services      []ServiceConfig      `json:"services"`
+	Workspace     WorkspaceConfig      `json:"workspace"`
+	Steps         []PipelineConfigStep `json:"steps"`
+	Cleanup       []string             `json:"cleanup"`
+}
+
+func (c PipelineConfig) Validate() error {
+	if c.MaxConcurrent < 0 {
+		return errors.New("max_concurrent cannot be negative")
+	}
+	if err := validateSteps(c.Steps); err != nil {
+		return err
+	}
+	if err := ValidateTarget(c.Target); err != nil {
+		return err
+	}
+	if err := ValidatePullPolicy(c.PullPolicy); err != nil {
+		return err
+	}
+	if err := c.Registry.Validate(); err != nil {
+		return err
+	}
+	if err := c.Resources.Validate(); err != nil {
+		return err
+	}
+	if err := c.Kubernetes.Validate(); err != nil {
+		return err
+	}
+	if err := c.Workspace.Validate(); err != nil {
+		return err
+	}
+	initContainers := []string{}
+	for _, container := range c.Kubernetes.InitContainers {
+		initContainers = append(initContainers, container.Name)
+	}
+	if err := validateServices(c.Services, initContainers); err != nil {
+		return err
+	}
+	return c.RunsOn.Validate()
+}
+
+// ConcurrencyConfig lets only one build of the group run at once, the group
+// is a template expanding ${project}, ${pipeline} and ${branch}.
+type ConcurrencyConfig struct {
+	Group            string `json:"group"`
+	CancelInProgress bool   `json:"cancel_in_progress"`
+}
+
+func (c ConcurrencyConfig) GroupFor(project, pipeline, branch string) string {
+	return strings.NewReplacer(
+		"${project}", project,
+		"${pipeline}", pipeline,
+		"${branch}", branch,
+	).Replace(c.Group)
 }
 
 type PipelineConfigStep struct {
-	Name     string   `json:"name"`
-	Commands []string `json:"commands"`
+	Name       string            `json:"name"`
+	Commands   []string          `json:"commands"`
+	ImageBuild *ImageBuildConfig `json:"image_build,omitempty"`
+}
+
+func (m *Pipeline) BeforeSave(tx *gorm.DB) error {
+	config := m.Config
+	if input, ok := tx.InstanceGet("input"); ok {
+		config = input.(PipelineInput).Config
+	}
+	return config.Validate()
 }
 
 func (m *Pipeline) BeforeDelete(tx *gorm.DB) error {
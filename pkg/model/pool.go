@@ -0,0 +1,45 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const DefaultPool = "default"
+
+const (
+	StrategyBinPacking = "binpack"
+	StrategySpread     = "spread"
+	StrategyLRU        = "lru"
+	StrategyWeighted   = "weighted"
+)
+
+var Strategies = []string{StrategyBinPacking, StrategySpread, StrategyLRU, StrategyWeighted}
+
+// WorkerPool configures how workers sharing the pool name are selected,
+// workers of pools without an entry use the spread strategy.
+type WorkerPool struct {
+	Name      string    `json:"name"       gorm:"primaryKey"`
+	Strategy  string    `json:"strategy"   gorm:"not null;default:spread"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:now()"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+type WorkerPoolInput struct {
+	Name     string `json:"name"`
+	Strategy string `json:"strategy"`
+}
+
+func (m *WorkerPool) BeforeSave(tx *gorm.DB) error {
+	strategy := m.Strategy
+	if input, ok := tx.InstanceGet("input"); ok {
+		strategy = input.(WorkerPoolInput).Strategy
+	}
+	if strategy != "" && !slices.Contains(Strategies, strategy) {
+		return fmt.Errorf("unknown strategy [%s] (expected one of %v)", strategy, Strategies)
+	}
+	return nil
+}
@@ -9,25 +9,42 @@ import (
 
 // TODO: cascading delete (secrets, issue: https://github.com/go-gorm/gorm/issues/5001)
 type Project struct {
-	Name      string     `json:"name"                gorm:"primaryKey"`
-	Repo      string     `json:"repo"                gorm:"not null"`
-	Variables []Variable `json:"variables,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Secrets   []Secret   `json:"secrets,omitempty"`
-	Pipelines []Pipeline `json:"pipelines,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	CreatedAt time.Time  `json:"created_at"          gorm:"default:now()"`
-	UpdatedAt time.Time  `json:"updated_at"          gorm:"default:now()"`
+	Name          string     `json:"name"                gorm:"primaryKey"`
+	Repo          string     `json:"repo"                gorm:"not null"`
+	MaxConcurrent int        `json:"max_concurrent"      gorm:"default:0"`
+	Weight        int        `json:"weight"              gorm:"default:1"`
+	Variables     []Variable `json:"variables,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Secrets       []Secret   `json:"secrets,omitempty"`
+	Pipelines     []Pipeline `json:"pipelines,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	CreatedAt     time.Time  `json:"created_at"          gorm:"default:now()"`
+	UpdatedAt     time.Time  `json:"updated_at"          gorm:"default:now()"`
 }
 
 type ProjectShort struct {
-	Name      string    `json:"name"`
-	Repo      string    `json:"repo"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Name          string    `json:"name"`
+	Repo          string    `json:"repo"`
+	MaxConcurrent int       `json:"max_concurrent"`
+	Weight        int       `json:"weight"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 type ProjectInput struct {
-	Name string `json:"name"`
-	Repo string `json:"repo"`
+	Name          string `json:"name"`
+	Repo          string `json:"repo"`
+	MaxConcurrent int    `json:"max_concurrent"`
+	Weight        int    `json:"weight"`
+}
+
+func (m *Project) BeforeSave(tx *gorm.DB) error {
+	input, ok := tx.InstanceGet("input")
+	if !ok {
+		return nil
+	}
+	if input.(ProjectInput).MaxConcurrent < 0 || input.(ProjectInput).Weight < 0 {
+		return errors.New("max_concurrent and weight cannot be negative")
+	}
+	return nil
 }
 
 func (m *Project) BeforeDelete(tx *gorm.DB) error {
@@ -1,18 +1,45 @@
 package model
 
 import (
+	"database/sql"
 	"time"
 )
 
+// Reasons why the build still waits in the queue after the last bind attempt.
+const (
+	WaitNoWorker                 = "no_worker"
+	WaitNoWorkerForConfiguration = "no_worker_for_configuration"
+	WaitProjectLimit             = "project_limit"
+	WaitPipelineLimit            = "pipeline_limit"
+	WaitConcurrencyGroup         = "concurrency_group"
+)
+
 type QueueElem struct {
-	ID        string       `json:"id"         gorm:"primaryKey"`
-	Context   QueueContext `json:"context"    gorm:"serializer:json;not null"`
-	CreatedAt time.Time    `json:"created_at" gorm:"default:now()"`
+	ID            string       `json:"id"              gorm:"primaryKey"`
+	Context       QueueContext `json:"context"         gorm:"serializer:json;not null"`
+	WaitReason    string       `json:"wait_reason"`
+	LastAttemptAt sql.NullTime `json:"last_attempt_at"`
+	CreatedAt     time.Time    `json:"created_at"      gorm:"default:now()"`
 }
 
 type QueueElemShort struct {
-	ID        string    `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            string       `json:"id"`
+	WaitReason    string       `json:"wait_reason"`
+	LastAttemptAt sql.NullTime `json:"last_attempt_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+type QueueStatus struct {
+	ID            string       `json:"id"`
+	Position      int          `json:"position"`
+	Priority      int          `json:"priority"`
+	WaitReason    string       `json:"wait_reason"`
+	LastAttemptAt sql.NullTime `json:"last_attempt_at"`
+	// Estimates based on durations of recent successful builds of the pipeline,
+	// null when there is no history or no worker to run on.
+	EstimatedStart    sql.NullTime `json:"estimated_start"`
+	EstimatedDuration string       `json:"estimated_duration,omitempty"`
+	CreatedAt         time.Time    `json:"created_at"`
 }
 
 type QueueContext struct {
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	PullAlways       = "always"
+	PullIfNotPresent = "if-not-present"
+	PullNever        = "never"
+)
+
+var PullPolicies = []string{PullAlways, PullIfNotPresent, PullNever}
+
+// RegistryConfig gives credentials for pulling images from private registry,
+// the password is a secret visible to the pipeline referenced by its key.
+type RegistryConfig struct {
+	Server         string `json:"server"`
+	Username       string `json:"username"`
+	PasswordSecret string `json:"password_secret"`
+}
+
+func ValidatePullPolicy(policy string) error {
+	if policy != "" && !slices.Contains(PullPolicies, policy) {
+		return fmt.Errorf("unknown pull_policy [%s] (expected one of %v)", policy, PullPolicies)
+	}
+	return nil
+}
+
+func (c RegistryConfig) Validate() error {
+	if c.Empty() {
+		return nil
+	}
+	if c.Server == "" || c.Username == "" || c.PasswordSecret == "" {
+		return errors.New("registry requires server, username and password_secret")
+	}
+	return nil
+}
+
+func (c RegistryConfig) Empty() bool {
+	return c == RegistryConfig{}
+}
+
+// Password looks the password up among secrets resolved for the build.
+func (c RegistryConfig) Password(secrets []Secret) (string, error) {
+	for _, secret := range secrets {
+		if secret.Key == c.PasswordSecret {
+			return secret.Value()
+		}
+	}
+	return "", fmt.Errorf("registry password secret [%s] not found", c.PasswordSecret)
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ResourceConfig is what a build asks for, requests are reserved on the
+// worker and limits are enforced on the container.
+type ResourceConfig struct {
+	Requests ResourceList `json:"requests"`
+	Limits   ResourceList `json:"limits"`
+}
+
+// ResourceList holds quantities in Kubernetes notation (e.g. "500m", "2Gi"),
+// empty ones are not constrained.
+type ResourceList struct {
+	CPU              string `json:"cpu"`
+	Memory           string `json:"memory"`
+	EphemeralStorage string `json:"ephemeral_storage"`
+}
+
+// ResourceUnits holds CPU in millicores, memory and storage in bytes.
+type ResourceUnits struct {
+	CPU              int64 `json:"cpu"`
+	Memory           int64 `json:"memory"`
+	EphemeralStorage int64 `json:"ephemeral_storage"`
+}
+
+func (c ResourceConfig) Validate() error {
+	if err := c.Requests.Validate(); err != nil {
+		return fmt.Errorf("resources.requests: %v", err)
+	}
+	if err := c.Limits.Validate(); err != nil {
+		return fmt.Errorf("resources.limits: %v", err)
+	}
+	requests, limits := c.Requests.Units(), c.Limits.Units()
+	if (limits.CPU != 0 && requests.CPU > limits.CPU) ||
+		(limits.Memory != 0 && requests.Memory > limits.Memory) ||
+		(limits.EphemeralStorage != 0 && requests.EphemeralStorage > limits.EphemeralStorage) {
+		return fmt.Errorf("resources: requests cannot exceed limits")
+	}
+	return nil
+}
+
+// Requested returns what is reserved for the build, limits stand in for
+// missing requests like they do in Kubernetes.
+func (c ResourceConfig) Requested() ResourceUnits {
+	requests, limits := c.Requests.Units(), c.Limits.Units()
+	if requests.CPU == 0 {
+		requests.CPU = limits.CPU
+	}
+	if requests.Memory == 0 {
+		requests.Memory = limits.Memory
+	}
+	if requests.EphemeralStorage == 0 {
+		requests.EphemeralStorage = limits.EphemeralStorage
+	}
+	return requests
+}
+
+func (l ResourceList) Validate() error {
+	for name, value := range map[string]string{"cpu": l.CPU, "memory": l.Memory, "ephemeral_storage": l.EphemeralStorage} {
+		if value == "" {
+			continue
+		}
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s quantity [%s]", name, value)
+		}
+		if q.Sign() < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+	return nil
+}
+
+func (l ResourceList) Empty() bool {
+	return l == ResourceList{}
+}
+
+// Units converts the list, invalid quantities count as zero.
+func (l ResourceList) Units() ResourceUnits {
+	parse := func(value string) resource.Quantity {
+		q, _ := resource.ParseQuantity(value)
+		return q
+	}
+	cpu, memory, storage := parse(l.CPU), parse(l.Memory), parse(l.EphemeralStorage)
+	return ResourceUnits{
+		CPU:              cpu.MilliValue(),
+		Memory:           memory.Value(),
+		EphemeralStorage: storage.Value(),
+	}
+}
+
+func (u ResourceUnits) Add(other ResourceUnits) ResourceUnits {
+	return ResourceUnits{
+		CPU:              u.CPU + other.CPU,
+		Memory:           u.Memory + other.Memory,
+		EphemeralStorage: u.EphemeralStorage + other.EphemeralStorage,
+	}
+}
+
+func (u ResourceUnits) Sub(other ResourceUnits) ResourceUnits {
+	return ResourceUnits{
+		CPU:              max(u.CPU-other.CPU, 0),
+		Memory:           max(u.Memory-other.Memory, 0),
+		EphemeralStorage: max(u.EphemeralStorage-other.EphemeralStorage, 0),
+	}
+}
+
+// FitsIn tells whether the units fit into capacity, zero capacity of a
+// resource means it is not accounted.
+func (u ResourceUnits) FitsIn(capacity ResourceUnits) bool {
+	return (capacity.CPU == 0 || u.CPU <= capacity.CPU) &&
+		(capacity.Memory == 0 || u.Memory <= capacity.Memory) &&
+		(capacity.EphemeralStorage == 0 || u.EphemeralStorage <= capacity.EphemeralStorage)
+}
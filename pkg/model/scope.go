@@ -0,0 +1,131 @@
+package model
+
+import (
+	"database/sql"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ScopeGlobal   = "global"
+	ScopeProject  = "project"
+	ScopePipeline = "pipeline"
+)
+
+type EffectiveVariable struct {
+	Key        string   `json:"key"`
+	Value      string   `json:"value"`
+	Path       string   `json:"path,omitempty"`
+	Scope      string   `json:"scope"`
+	Overridden []string `json:"overridden,omitempty"`
+}
+
+type scoped interface {
+	Variable | Secret
+	scopeKey() string
+	scope() string
+}
+
+func (m Variable) scopeKey() string { return m.Key }
+func (m Variable) scope() string    { return scopeOf(m.ProjectName, m.PipelineName) }
+func (m Secret) scopeKey() string   { return m.Key }
+func (m Secret) scope() string      { return scopeOf(m.ProjectName, m.PipelineName) }
+
+// ResolveVariables returns variables visible to the given pipeline, where
+// pipeline entries override project entries, which override global ones.
+func ResolveVariables(tx *gorm.DB, projectName, pipelineName string) ([]Variable, error) {
+	variables, _, err := resolveScoped[Variable](tx, projectName, pipelineName)
+	return variables, err
+}
+
+// ResolveSecrets works like ResolveVariables for secrets.
+func ResolveSecrets(tx *gorm.DB, projectName, pipelineName string) ([]Secret, error) {
+	secrets, _, err := resolveScoped[Secret](tx, projectName, pipelineName)
+	return secrets, err
+}
+
+func EffectiveVariables(tx *gorm.DB, projectName, pipelineName string) ([]EffectiveVariable, error) {
+	variables, overridden, err := resolveScoped[Variable](tx, projectName, pipelineName)
+	if err != nil {
+		return []EffectiveVariable{}, err
+	}
+
+	effective := []EffectiveVariable{}
+	for _, variable := range variables {
+		effective = append(effective, EffectiveVariable{
+			Key:        variable.Key,
+			Value:      variable.Value,
+			Path:       variable.Path,
+			Scope:      variable.scope(),
+			Overridden: overridden[variable.Key],
+		})
+	}
+	return effective, nil
+}
+
+func resolveScoped[T scoped](tx *gorm.DB, projectName, pipelineName string) ([]T, map[string][]string, error) {
+	var entries []T
+	if err := tx.Where(
+		"(project_name IS NULL AND pipeline_name IS NULL) OR "+
+			"(project_name = ? AND pipeline_name IS NULL) OR "+
+			"(project_name = ? AND pipeline_name = ?)",
+		projectName, projectName, pipelineName,
+	).Find(&entries).Error; err != nil {
+		return []T{}, map[string][]string{}, err
+	}
+
+	resolved := map[string]T{}
+	overridden := map[string][]string{}
+	for _, entry := range entries {
+		key := entry.scopeKey()
+		prev, ok := resolved[key]
+		if !ok {
+			resolved[key] = entry
+			continue
+		}
+		if scopeRank(entry.scope()) > scopeRank(prev.scope()) {
+			resolved[key] = entry
+			overridden[key] = append(overridden[key], prev.scope())
+		} else {
+			overridden[key] = append(overridden[key], entry.scope())
+		}
+	}
+
+	keys := make([]string, 0, len(resolved))
+	for key := range resolved {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make([]T, 0, len(keys))
+	for _, key := range keys {
+		sort.Slice(overridden[key], func(i, j int) bool {
+			return scopeRank(overridden[key][i]) > scopeRank(overridden[key][j])
+		})
+		out = append(out, resolved[key])
+	}
+	return out, overridden, nil
+}
+
+func scopeOf(projectName, pipelineName sql.NullString) string {
+	switch {
+	case pipelineName.Valid:
+		return ScopePipeline
+	case projectName.Valid:
+		return ScopeProject
+	default:
+		return ScopeGlobal
+	}
+}
+
+func scopeRank(scope string) int {
+	switch scope {
+	case ScopePipeline:
+		return 2
+	case ScopeProject:
+		return 1
+	default:
+		return 0
+	}
+}
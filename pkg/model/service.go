@@ -0,0 +1,95 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	defaultHealthCheckInterval = 2 * time.Second
+	defaultHealthCheckTimeout  = 2 * time.Minute
+)
+
+// ServiceConfig is a container started next to the build (e.g. a database),
+// reachable from the build under its name.
+type ServiceConfig struct {
+	Name        string             `json:"name"`
+	Image       string             `json:"image"`
+	Command     []string           `json:"command"`
+	Env         map[string]string  `json:"env"`
+	HealthCheck ServiceHealthCheck `json:"health_check"`
+}
+
+// ServiceHealthCheck is a shell command run in the service container, the
+// first step starts once it succeeds. Without a command the service only has
+// to be running.
+type ServiceHealthCheck struct {
+	Command  string `json:"command"`
+	Interval string `json:"interval"`
+	Timeout  string `json:"timeout"`
+}
+
+func (c ServiceConfig) Validate() error {
+	if errs := validation.IsDNS1123Label(c.Name); len(errs) != 0 {
+		return fmt.Errorf("services [%s]: %s", c.Name, strings.Join(errs, ", "))
+	}
+	if c.Name == KubernetesWorkerContainer {
+		return fmt.Errorf("services [%s]: name is reserved", c.Name)
+	}
+	if c.Image == "" {
+		return fmt.Errorf("services [%s]: missing image", c.Name)
+	}
+	if _, _, err := c.HealthCheck.Durations(); err != nil {
+		return fmt.Errorf("services [%s]: %v", c.Name, err)
+	}
+	return nil
+}
+
+// EnvList returns environment in KEY=value form.
+func (c ServiceConfig) EnvList() []string {
+	env := make([]string, 0, len(c.Env))
+	for key, value := range c.Env {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// Durations returns the interval between checks and how long to wait for the
+// service to become healthy.
+func (h ServiceHealthCheck) Durations() (time.Duration, time.Duration, error) {
+	interval, timeout := defaultHealthCheckInterval, defaultHealthCheckTimeout
+	var err error
+	if h.Interval != "" {
+		if interval, err = time.ParseDuration(h.Interval); err != nil || interval <= 0 {
+			return 0, 0, fmt.Errorf("invalid health_check.interval [%s]", h.Interval)
+		}
+	}
+	if h.Timeout != "" {
+		if timeout, err = time.ParseDuration(h.Timeout); err != nil || timeout <= 0 {
+			return 0, 0, fmt.Errorf("invalid health_check.timeout [%s]", h.Timeout)
+		}
+	}
+	return interval, timeout, nil
+}
+
+func validateServices(services []ServiceConfig, initContainers []string) error {
+	names := map[string]bool{}
+	for _, name := range initContainers {
+		names[name] = true
+	}
+	for _, service := range services {
+		if err := service.Validate(); err != nil {
+			return err
+		}
+		if names[service.Name] {
+			return fmt.Errorf("services [%s]: duplicate name", service.Name)
+		}
+		names[service.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RoleViewer    = "viewer"
+	RoleDeveloper = "developer"
+	RoleAdmin     = "admin"
+)
+
+type User struct {
+	Name  string `json:"name"             gorm:"primaryKey"`
+	Email string `json:"email"            gorm:"index"`
+	// OIDC identity (issuer and subject) the user logs in with, empty for
+	// users created through the API.
+	OIDCIssuer  sql.NullString `json:"-"                gorm:"uniqueIndex:idx_users_oidc"`
+	OIDCSubject sql.NullString `json:"-"                gorm:"uniqueIndex:idx_users_oidc"`
+	Teams       []Team         `json:"teams,omitempty"  gorm:"many2many:team_members;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Tokens      []Token        `json:"tokens,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	CreatedAt   time.Time      `json:"created_at"       gorm:"default:now()"`
+	UpdatedAt   time.Time      `json:"updated_at"       gorm:"default:now()"`
+}
+
+type UserShort struct {
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UserInput struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type Team struct {
+	Name      string    `json:"name"            gorm:"primaryKey"`
+	Users     []User    `json:"users,omitempty" gorm:"many2many:team_members;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at"      gorm:"default:now()"`
+	UpdatedAt time.Time `json:"updated_at"      gorm:"default:now()"`
+}
+
+type TeamShort struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TeamInput struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+type RoleBinding struct {
+	ID          uint           `json:"id"           gorm:"primaryKey"`
+	UserName    sql.NullString `json:"user_name"    gorm:"index"`
+	TeamName    sql.NullString `json:"team_name"    gorm:"index"`
+	ProjectName sql.NullString `json:"project_name" gorm:"index"`
+	Role        string         `json:"role"         gorm:"not null"`
+	CreatedAt   time.Time      `json:"created_at"   gorm:"default:now()"`
+}
+
+type RoleBindingInput struct {
+	UserName    string `json:"user_name"`
+	TeamName    string `json:"team_name"`
+	ProjectName string `json:"project_name"`
+	Role        string `json:"role"`
+}
+
+type Token struct {
+	ID         uint         `json:"id"           gorm:"primaryKey"`
+	Name       string       `json:"name"         gorm:"not null"`
+	UserName   string       `json:"user_name"    gorm:"not null;index"`
+	Hash       string       `json:"-"            gorm:"not null;uniqueIndex"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"   gorm:"default:now()"`
+}
+
+type TokenInput struct {
+	Name     string `json:"name"`
+	UserName string `json:"user_name"`
+	TTL      string `json:"ttl"`
+}
+
+func (m *Team) BeforeSave(tx *gorm.DB) error {
+	input, ok := tx.InstanceGet("input")
+	if !ok {
+		return nil
+	}
+	members := input.(TeamInput).Members
+	var count int64
+	if err := tx.Session(&gorm.Session{NewDB: true}).Model(&User{}).Where("name IN ?", members).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(members) {
+		return errors.New("team members must be existing users")
+	}
+	return nil
+}
+
+func (m *Team) AfterUpdate(tx *gorm.DB) error {
+	input, ok := tx.InstanceGet("input")
+	if !ok {
+		return nil
+	}
+	return tx.Session(&gorm.Session{NewDB: true}).Model(&Team{Name: m.Name}).Association("Users").Replace(TeamMembers(input.(TeamInput).Members))
+}
+
+func TeamMembers(names []string) []User {
+	users := []User{}
+	for _, name := range names {
+		users = append(users, User{Name: name})
+	}
+	return users
+}
+
+func (m *RoleBinding) BeforeCreate(tx *gorm.DB) error {
+	if !IsRole(m.Role) {
+		return errors.New("unknown role [" + m.Role + "]")
+	}
+	if m.UserName.Valid == m.TeamName.Valid {
+		return errors.New("role binding requires exactly one of user or team")
+	}
+	return nil
+}
+
+func IsRole(role string) bool {
+	return slices.Contains([]string{RoleViewer, RoleDeveloper, RoleAdmin}, role)
+}
+
+// RoleRank orders roles so that a higher rank grants everything a lower one does.
+func RoleRank(role string) int {
+	switch role {
+	case RoleAdmin:
+		return 3
+	case RoleDeveloper:
+		return 2
+	case RoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (m Token) Expired() bool {
+	return m.ExpiresAt.Valid && m.ExpiresAt.Time.Before(time.Now())
+}
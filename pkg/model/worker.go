@@ -1,13 +1,11 @@
 package model
 
 import (
+	"database/sql"
 	"errors"
-	"fmt"
 	"time"
 
-	"github.com/gg-mike/ccli/pkg/docker"
 	"github.com/gg-mike/ccli/pkg/scheduler"
-	"github.com/gg-mike/ccli/pkg/ssh"
 	"github.com/gg-mike/ccli/pkg/vault"
 	"gorm.io/gorm"
 )
@@ -18,39 +16,84 @@ const (
 	WorkerUnreachable = "unreachable"
 )
 
+// Worker types, builds on a worker are run by the executor registered for
+// its type.
 const (
-	WorkerMin      = "min"
-	WorkerBalanced = "balance"
-	WorkerMax      = "max"
+	WorkerDocker     = "docker"
+	WorkerSSH        = "ssh"
+	WorkerAgent      = "agent"
+	WorkerLocal      = "local"
+	WorkerKubernetes = "kubernetes"
+)
+
+const (
+	HostKeyTrusted = "trusted"
+	HostKeyPending = "pending"
 )
 
 type Worker struct {
-	Name         string    `json:"name"             gorm:"primaryKey"`
-	Address      string    `json:"address"          gorm:"not null"`
-	System       string    `json:"system"           gorm:"not null"`
-	Username     string    `json:"username"         gorm:"not null"`
-	IsStatic     bool      `json:"is_static"        gorm:"not null"`
-	Status       string    `json:"status"           gorm:"default:idle"`
-	Strategy     string    `json:"strategy"         gorm:"default:balance"`
-	ActiveBuilds int       `json:"active_builds"    gorm:"default:0"`
-	Capacity     int       `json:"capacity"         gorm:"default:0"`
-	Builds       []Build   `json:"builds,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
-	CreatedAt    time.Time `json:"created_at"       gorm:"default:now()"`
-	UpdatedAt    time.Time `json:"updated_at"       gorm:"default:now()"`
+	Name         string        `json:"name"             gorm:"primaryKey"`
+	Address      string        `json:"address"          gorm:"not null"`
+	System       string        `json:"system"           gorm:"not null"`
+	Username     string        `json:"username"         gorm:"not null"`
+	Type         string        `json:"type"`
+	IsStatic     bool          `json:"is_static"        gorm:"not null"`
+	IsAgent      bool          `json:"is_agent"         gorm:"default:false"`
+	AgentKey     string        `json:"-"`
+	Ephemeral    bool          `json:"ephemeral"        gorm:"default:false"`
+	Status       string        `json:"status"           gorm:"default:idle"`
+	Pool         string        `json:"pool"             gorm:"not null;default:default"`
+	Strategy     string        `json:"strategy"` // Deprecated: set strategy on the worker pool.
+	ActiveBuilds int           `json:"active_builds"    gorm:"default:0"`
+	Capacity     int           `json:"capacity"         gorm:"default:0"`
+	Resources    ResourceList  `json:"resources"        gorm:"serializer:json"`
+	Allocated    ResourceUnits `json:"allocated"        gorm:"embedded;embeddedPrefix:allocated_"`
+	Labels       Labels        `json:"labels"           gorm:"serializer:json"`
+	Local        LocalConfig   `json:"local"            gorm:"serializer:json"`
+	Cluster      ClusterConfig `json:"cluster"          gorm:"serializer:json"`
+	Draining     bool          `json:"draining"         gorm:"default:false"`
+	DrainBy      sql.NullTime  `json:"drain_by"`
+	StatusReason string        `json:"status_reason"`
+	LastSeenAt   sql.NullTime  `json:"last_seen_at"`
+	LastBoundAt  sql.NullTime  `json:"last_bound_at"`
+	Failures     int           `json:"failures"         gorm:"default:0"`
+	Successes    int           `json:"-"                gorm:"default:0"`
+	HostKey      string        `json:"-"`
+	HostKeyFP    string        `json:"host_key_fingerprint"`
+	HostKeyState string        `json:"host_key_state"`
+	Builds       []Build       `json:"builds,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	CreatedAt    time.Time     `json:"created_at"       gorm:"default:now()"`
+	UpdatedAt    time.Time     `json:"updated_at"       gorm:"default:now()"`
 }
 
 type WorkerShort struct {
-	Name         string    `json:"name"`
-	Address      string    `json:"address"`
-	System       string    `json:"system"`
-	Username     string    `json:"username"`
-	IsStatic     bool      `json:"is_static"`
-	Status       string    `json:"status"`
-	Strategy     string    `json:"strategy"`
-	ActiveBuilds int       `json:"active_builds"`
-	Capacity     int       `json:"capacity"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	Name         string        `json:"name"`
+	Address      string        `json:"address"`
+	System       string        `json:"system"`
+	Username     string        `json:"username"`
+	Type         string        `json:"type"`
+	IsStatic     bool          `json:"is_static"`
+	IsAgent      bool          `json:"is_agent"`
+	Ephemeral    bool          `json:"ephemeral"`
+	Status       string        `json:"status"`
+	Pool         string        `json:"pool"`
+	Strategy     string        `json:"strategy"` // Deprecated: set strategy on the worker pool.
+	ActiveBuilds int           `json:"active_builds"`
+	Capacity     int           `json:"capacity"`
+	Resources    ResourceList  `json:"resources"     gorm:"serializer:json"`
+	Allocated    ResourceUnits `json:"allocated"     gorm:"embedded;embeddedPrefix:allocated_"`
+	Labels       Labels        `json:"labels"        gorm:"serializer:json"`
+	Local        LocalConfig   `json:"local"         gorm:"serializer:json"`
+	Cluster      ClusterConfig `json:"cluster"       gorm:"serializer:json"`
+	Draining     bool          `json:"draining"`
+	DrainBy      sql.NullTime  `json:"drain_by"`
+	StatusReason string        `json:"status_reason"`
+	LastSeenAt   sql.NullTime  `json:"last_seen_at"`
+	Failures     int           `json:"failures"`
+	HostKeyFP    string        `json:"host_key_fingerprint"`
+	HostKeyState string        `json:"host_key_state"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
 }
 
 type WorkerInput struct {
@@ -58,30 +101,65 @@ type WorkerInput struct {
 	Address    string `json:"address"`
 	System     string `json:"system"`
 	IsStatic   bool   `json:"is_static"`
-	Strategy   string `json:"strategy"`
+	Pool       string `json:"pool"`
 	Username   string `json:"username"`
 	PrivateKey string `json:"private_key"`
 	Capacity   int    `json:"capacity"`
+	Labels     Labels `json:"labels"`
+	// Resources available to builds, empty ones are not accounted.
+	Resources ResourceList `json:"resources"`
+	// Type selects the executor, derived from is_static when empty.
+	Type string `json:"type"`
+	// Sandbox of the local worker.
+	Local LocalConfig `json:"local"`
+	// Cluster of the Kubernetes worker.
+	Cluster ClusterConfig `json:"cluster"`
+	// Kubeconfig of the outer cluster, it is kept in vault.
+	Kubeconfig string `json:"kubeconfig"`
+	// Expected host key fingerprint (SHA256:...), pins the key on registration.
+	HostKeyFP string `json:"host_key_fingerprint"`
+	// Trust the host key seen on registration without later confirmation.
+	TrustHostKey bool `json:"trust_host_key"`
+	// Deprecated: selection strategy is configured per pool (see /pools), the
+	// value is kept for older clients and ignored.
+	Strategy string `json:"strategy"`
+}
+
+func (m *Worker) BeforeSave(tx *gorm.DB) error {
+	m.Type = m.ExecutorType()
+	if m.Type == WorkerLocal {
+		// Runs host shell, pipelines select it by system like static workers.
+		m.IsStatic = true
+	}
+	return m.Resources.Validate()
+}
+
+// ExecutorType returns the type of the worker, workers saved without one are
+// told apart by their flags.
+func (m Worker) ExecutorType() string {
+	switch {
+	case m.Type != "":
+		return m.Type
+	case m.IsAgent:
+		return WorkerAgent
+	case m.IsStatic:
+		return WorkerSSH
+	}
+	return WorkerDocker
 }
 
 func (m *Worker) BeforeCreate(tx *gorm.DB) error {
-	if m.IsStatic {
-		privateKey, ok := getPK(tx)
-		if !ok {
-			return errors.New("no private_key field given in instance")
-		}
-		if !testConnection(*m, privateKey) {
-			m.Status = WorkerUnreachable
-		}
-	} else if err := docker.NewClient(m.Address); err != nil {
-		return err
+	if creator, ok := workerHooks(m.ExecutorType()).(WorkerCreator); ok {
+		return creator.CreateWorker(tx, m, getInput(tx))
 	}
 	return nil
 }
 
 func (m *Worker) AfterCreate(tx *gorm.DB) error {
-	privateKey, _ := getPK(tx)
-	return vault.SetStr(m.Name, privateKey)
+	if secrets, ok := workerHooks(m.ExecutorType()).(WorkerSecrets); ok {
+		return secrets.StoreSecrets(*m, getInput(tx))
+	}
+	return nil
 }
 
 func (m *Worker) AfterSave(tx *gorm.DB) error {
@@ -89,50 +167,21 @@ func (m *Worker) AfterSave(tx *gorm.DB) error {
 	return nil
 }
 
-func (m *Worker) BeforeUpdate(tx *gorm.DB) error {
-	if _, ok := getPK(tx); !ok {
-		return nil
-	}
-	return vault.Del(m.Name)
-}
-
 func (m *Worker) AfterUpdate(tx *gorm.DB) error {
 	prev, ok := tx.InstanceGet("prev")
 	if !ok {
 		return errors.New("prev worker not given")
 	}
-	if m.IsStatic {
-		privateKey, ok := getPK(tx)
-		if !ok {
-			pKey, err := vault.GetStr(m.Name)
-			if err != nil {
-				return fmt.Errorf("error during retrieving private key: %v", err)
+	if prevType := prev.(Worker).ExecutorType(); prevType != m.ExecutorType() {
+		if remover, ok := workerHooks(prevType).(WorkerRemover); ok {
+			if err := remover.RemoveWorker(prev.(Worker)); err != nil {
+				return err
 			}
-			privateKey = pKey
-		}
-		var status string
-		if !testConnection(*m, privateKey) {
-			status = WorkerUnreachable
-		} else if prev.(Worker).Status != WorkerUnreachable {
-			status = prev.(Worker).Status
-		} else {
-			status = WorkerIdle
-		}
-		if err := tx.Model(&m).UpdateColumn("status", status).Error; err != nil {
-			return err
-		}
-		if !ok {
-			return nil
-		}
-		return vault.SetStr(m.Name, privateKey)
-	} else {
-		if err := docker.DeleteClient(prev.(Worker).Address); err != nil {
-			return err
-		}
-		if err := docker.NewClient(m.Address); err != nil {
-			return err
 		}
 	}
+	if updater, ok := workerHooks(m.ExecutorType()).(WorkerUpdater); ok {
+		return updater.UpdateWorker(tx, prev.(Worker), m, getInput(tx))
+	}
 	return nil
 }
 
@@ -146,27 +195,97 @@ func (m *Worker) BeforeDelete(tx *gorm.DB) error {
 }
 
 func (m *Worker) AfterDelete(tx *gorm.DB) error {
-	if err := docker.DeleteClient(m.Address); err != nil {
+	if remover, ok := workerHooks(m.ExecutorType()).(WorkerRemover); ok {
+		return remover.RemoveWorker(*m)
+	}
+	return nil
+}
+
+// Drain stops binding new builds to the worker, builds still running on it
+// are canceled once the optional deadline passes.
+func (m *Worker) Drain(tx *gorm.DB, deadline sql.NullTime) error {
+	m.Draining = true
+	m.DrainBy = deadline
+	return tx.Model(m).UpdateColumns(map[string]any{
+		"draining": m.Draining,
+		"drain_by": m.DrainBy,
+	}).Error
+}
+
+func (m *Worker) Undrain(tx *gorm.DB) error {
+	m.Draining = false
+	m.DrainBy = sql.NullTime{}
+	if err := tx.Model(m).UpdateColumns(map[string]any{
+		"draining": m.Draining,
+		"drain_by": m.DrainBy,
+	}).Error; err != nil {
 		return err
 	}
-	return vault.Del(m.Name)
+	go scheduler.Get().ChangeInWorkers()
+	return nil
+}
+
+// Fits reports whether the worker has a free slot and resources left for
+// the requested units.
+func (m Worker) Fits(requested ResourceUnits) bool {
+	if m.ActiveBuilds >= m.Capacity {
+		return false
+	}
+	return m.Resources.Empty() || m.Allocated.Add(requested).FitsIn(m.Resources.Units())
 }
 
-func testConnection(worker Worker, privateKey string) bool {
-	return ssh.CheckConnection(worker.Username, worker.Address, privateKey) == nil
+// Accommodates reports whether the worker could run a build requesting the
+// units at all, i.e. once it is empty.
+func (m Worker) Accommodates(requested ResourceUnits) bool {
+	if m.Capacity <= 0 {
+		return false
+	}
+	return m.Resources.Empty() || requested.FitsIn(m.Resources.Units())
 }
 
-func getPK(tx *gorm.DB) (string, bool) {
+// Drained reports whether the worker is draining and has no builds left.
+func (m Worker) Drained() bool {
+	return m.Draining && m.ActiveBuilds == 0
+}
+
+// DrainExpired reports whether the drain deadline passed with builds left.
+func (m Worker) DrainExpired(now time.Time) bool {
+	return m.Draining && m.DrainBy.Valid && m.DrainBy.Time.Before(now) && m.ActiveBuilds > 0
+}
+
+// CancelBuilds cancels all builds running on the worker, they stop before
+// their next step. Returned builds have to be finished by the scheduler once
+// tx commits.
+func (m Worker) CancelBuilds(tx *gorm.DB) ([]string, error) {
+	var builds []Build
+	if err := tx.Where("worker_name = ? AND status = ?", m.Name, BuildRunning).Find(&builds).Error; err != nil {
+		return []string{}, err
+	}
+	canceled := []string{}
+	for _, build := range builds {
+		ok, err := build.Cancel(tx, "drain deadline of worker ["+m.Name+"] passed")
+		if err != nil {
+			return canceled, err
+		}
+		if ok {
+			canceled = append(canceled, build.ID())
+		}
+	}
+	return canceled, nil
+}
+
+func getInput(tx *gorm.DB) *WorkerInput {
 	input, ok := tx.InstanceGet("input")
 	if !ok {
-		return "", false
+		return nil
 	}
-	return input.(WorkerInput).PrivateKey, ok
+	workerInput := input.(WorkerInput)
+	return &workerInput
 }
 
 func (m Worker) PK() (string, error) {
-	if m.IsStatic {
-		return vault.GetStr(m.Name)
+	if t := m.ExecutorType(); t != WorkerSSH {
+		return "", errors.New(t + " worker does not have a private key")
 	}
-	return "", errors.New("docker host worker does not have a private key")
+	return vault.GetStr(m.Name)
 }
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"slices"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// updateTx returns transaction the handler passes to update hooks. Hooks of
+// the models in tests must not reach vault, Docker or database.
+func updateTx(prev any) *gorm.DB {
+	tx := &gorm.DB{Statement: &gorm.Statement{}}
+	return tx.InstanceSet("prev", prev).InstanceSet("input", WorkerInput{})
+}
+
+// recordingHooks records hooks called on workers of its type.
+type recordingHooks struct {
+	calls *[]string
+}
+
+func (h recordingHooks) CreateWorker(tx *gorm.DB, worker *Worker, input *WorkerInput) error {
+	*h.calls = append(*h.calls, "create "+worker.Name)
+	return nil
+}
+
+func (h recordingHooks) StoreSecrets(worker Worker, input *WorkerInput) error {
+	*h.calls = append(*h.calls, "secrets "+worker.Name)
+	return nil
+}
+
+func (h recordingHooks) UpdateWorker(tx *gorm.DB, prev Worker, worker *Worker, input *WorkerInput) error {
+	*h.calls = append(*h.calls, "update "+prev.Name+" to "+worker.Name)
+	return nil
+}
+
+func (h recordingHooks) RemoveWorker(worker Worker) error {
+	*h.calls = append(*h.calls, "remove "+worker.Name)
+	return nil
+}
+
+func TestWorkerHooksDispatchedByType(t *testing.T) {
+	calls := []string{}
+	SetWorkerHooks(func(workerType string) any {
+		if workerType == WorkerSSH {
+			return recordingHooks{calls: &calls}
+		}
+		return nil
+	})
+	defer SetWorkerHooks(func(string) any { return nil })
+
+	ssh := Worker{Name: "ssh", IsStatic: true}
+	docker := Worker{Name: "docker"}
+	tests := []struct {
+		name  string
+		hook  func() error
+		calls []string
+	}{
+		{"create", func() error { return ssh.BeforeCreate(updateTx(nil)) }, []string{"create ssh"}},
+		{"created", func() error { return ssh.AfterCreate(updateTx(nil)) }, []string{"secrets ssh"}},
+		{"create without hooks", func() error { return docker.BeforeCreate(updateTx(nil)) }, []string{}},
+		{"update", func() error { return ssh.AfterUpdate(updateTx(ssh)) }, []string{"update ssh to ssh"}},
+		{"update to other type", func() error { return docker.AfterUpdate(updateTx(ssh)) }, []string{"remove ssh"}},
+		{"update from other type", func() error { return ssh.AfterUpdate(updateTx(docker)) }, []string{"update docker to ssh"}},
+		{"delete", func() error { return ssh.AfterDelete(updateTx(nil)) }, []string{"remove ssh"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = []string{}
+			if err := tt.hook(); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(calls, tt.calls) {
+				t.Errorf("hooks called %v, want %v", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestWorkerPKOnlyOnSSH(t *testing.T) {
+	for _, worker := range []Worker{
+		{Name: "local", Type: WorkerLocal, IsStatic: true},
+		{Name: "docker"},
+		{Name: "agent", IsAgent: true, IsStatic: true},
+		{Name: "k8s", Type: WorkerKubernetes},
+	} {
+		if _, err := worker.PK(); err == nil {
+			t.Errorf("%s worker has private key", worker.ExecutorType())
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// WorkspacePath is where container builds mount the volume with the work dir.
+const WorkspacePath = "/ccli/workspace"
+
+const DefaultWorkspaceSize = "1Gi"
+
+// WorkspaceConfig describes volume backing the work dir of container builds,
+// storage class selects Docker volume driver or Kubernetes storage class.
+type WorkspaceConfig struct {
+	StorageClass string `json:"storage_class"`
+	Size         string `json:"size"`
+}
+
+func (c WorkspaceConfig) Validate() error {
+	if c.Size == "" {
+		return nil
+	}
+	q, err := resource.ParseQuantity(c.Size)
+	if err != nil {
+		return fmt.Errorf("invalid workspace size [%s]", c.Size)
+	}
+	if q.Sign() <= 0 {
+		return errors.New("workspace size must be positive")
+	}
+	return nil
+}
+
+// Bytes returns the size of the workspace, zero when not set.
+func (c WorkspaceConfig) Bytes() int64 {
+	q, _ := resource.ParseQuantity(c.Size)
+	return q.Value()
+}
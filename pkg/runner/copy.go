@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Files travel through the shell as base64, split into commands of this size.
+const copyChunk = 48 * 1024
+
+// Capture runs the command and returns its binary output, the command and its
+// output are kept out of OnCmd and OnOut. Output is buffered in a temporary
+// file so that failure of the command fails the capture.
+func (r *Runner) Capture(command string) ([]byte, error) {
+	encoded := strings.Builder{}
+	wrapped := fmt.Sprintf(`{ f=$(mktemp) && %s > "$f" 2>/dev/null && base64 "$f"; rc=$?; rm -f "$f"; [ $rc -eq 0 ]; }`, command)
+	err := r.quiet(func(out string) { encoded.WriteString(strings.TrimSpace(out)) }, []string{wrapped})
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(encoded.String())
+}
+
+// CopyIn writes content to the file at path in the build environment.
+func (r *Runner) CopyIn(path string, content io.Reader) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	tmp := Quote(path + ".b64")
+	commands := []string{": > " + tmp}
+	for len(encoded) > 0 {
+		chunk := encoded[:min(copyChunk, len(encoded))]
+		encoded = encoded[len(chunk):]
+		commands = append(commands, fmt.Sprintf("printf '%%s' '%s' >> %s", chunk, tmp))
+	}
+	commands = append(commands, fmt.Sprintf("base64 -d %s > %s && rm -f %s", tmp, Quote(path), tmp))
+	return r.quiet(func(string) {}, commands)
+}
+
+// CopyOut reads the file at path in the build environment into w.
+func (r *Runner) CopyOut(path string, w io.Writer) error {
+	data, err := r.Capture("cat " + Quote(path))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, bytes.NewReader(data))
+	return err
+}
+
+func (r *Runner) quiet(onOut func(string), commands []string) error {
+	prevCmd, prevOut := r.OnCmd, r.OnOut
+	defer func() { r.OnCmd, r.OnOut = prevCmd, prevOut }()
+	r.OnCmd = func(string, int, int) {}
+	r.OnOut = onOut
+	return r.Run(commands)
+}
+
+// Quote makes value a single shell word.
+func Quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,18 @@
+package runner
+
+import "io"
+
+// ImageSpec describes image built from a tar archive of the build context.
+type ImageSpec struct {
+	Dockerfile string
+	Tags       []string
+	BuildArgs  map[string]string
+	Labels     map[string]string
+	Push       bool
+}
+
+// ImageBuilder builds images next to the build, output of the build is passed
+// to out. It returns digest of the pushed image or ID of the local one.
+type ImageBuilder interface {
+	BuildImage(spec ImageSpec, buildContext io.Reader, out func(string)) (string, error)
+}
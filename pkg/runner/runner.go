@@ -8,7 +8,10 @@ import (
 	"strings"
 )
 
-var ErrBuildFailed = errors.New("build failed")
+var (
+	ErrBuildFailed  = errors.New("build failed")
+	ErrStreamClosed = errors.New("output closed before command finished")
+)
 
 type Runner struct {
 	writer  *bufio.Writer
@@ -17,6 +20,10 @@ type Runner struct {
 	OnCmd      func(cmd string, idx int, total int)
 	OnOut      func(out string)
 	OnShutdown func() error
+	// Builder is nil when the worker cannot build images.
+	Builder ImageBuilder
+	// Workspace is nil when the work dir lives in the home directory.
+	Workspace Workspace
 }
 
 func NewRunner(writer io.Writer, reader io.Reader) *Runner {
@@ -56,8 +63,10 @@ func (r *Runner) Run(commands []string) error {
 				} else {
 					r.OnOut(text)
 				}
+			} else if err := r.scanner.Err(); err != nil {
+				return err
 			} else {
-				return r.scanner.Err()
+				return ErrStreamClosed
 			}
 		}
 	}
@@ -0,0 +1,8 @@
+package runner
+
+// Workspace is volume with the work dir which outlives the runner, it is
+// reused by runners of the same build and removed once the build ends.
+type Workspace interface {
+	Path() string
+	Remove() error
+}
@@ -2,33 +2,52 @@ package serve
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"os/signal"
 	"syscall"
 	"time"
 
 	docs "github.com/gg-mike/ccli/docs"
+	"github.com/gg-mike/ccli/pkg/agent"
 	"github.com/gg-mike/ccli/pkg/api/handler"
 	"github.com/gg-mike/ccli/pkg/api/router"
+	"github.com/gg-mike/ccli/pkg/auth"
+	"github.com/gg-mike/ccli/pkg/autoscale"
 	"github.com/gg-mike/ccli/pkg/db"
 	"github.com/gg-mike/ccli/pkg/docker"
 	"github.com/gg-mike/ccli/pkg/engine"
-	"github.com/gg-mike/ccli/pkg/engine/k8s"
 	"github.com/gg-mike/ccli/pkg/engine/standalone"
+	"github.com/gg-mike/ccli/pkg/health"
+	"github.com/gg-mike/ccli/pkg/kubernetes"
 	"github.com/gg-mike/ccli/pkg/log"
+	"github.com/gg-mike/ccli/pkg/model"
 	"github.com/gg-mike/ccli/pkg/scheduler"
+	"github.com/gg-mike/ccli/pkg/ssh"
 	"github.com/gg-mike/ccli/pkg/vault"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"gorm.io/gorm"
 )
 
+// ClusterWorker is name of the worker registered for the cluster given by
+// k8s flags.
+const ClusterWorker = "kubernetes"
+
 type Flags struct {
-	Address   string
-	DbUrl     string
-	Vault     vault.Config
-	Scheduler string
-	K8s       k8s.Config
+	Address string
+	DbUrl   string
+	Vault   vault.Config
+	Engine  engine.Config
+	// K8s is the cluster registered as worker, nil when no k8s flag is set.
+	K8s           *model.ClusterConfig
+	K8sKubeconfig string
+	K8sCapacity   int
+	Health        health.Config
+	Autoscale     autoscale.Config
+	Dind          autoscale.DockerProvisioner
+	Auth          auth.Config
 }
 
 type Handler struct {
@@ -37,6 +56,8 @@ type Handler struct {
 	srv    *http.Server
 	state  *handler.State
 	engine *engine.Engine
+	health *health.Monitor
+	scaler *autoscale.Autoscaler
 }
 
 func NewHandler(logger log.Logger, f *Flags) *Handler {
@@ -46,15 +67,10 @@ func NewHandler(logger log.Logger, f *Flags) *Handler {
 		state:  handler.NewState(),
 	}
 
-	if h.flags.Scheduler == "standalone" {
-		h.engine = engine.NewEngine(logger, standalone.NewBinder(logger))
-	} else {
-		binder, err := k8s.NewBinder(logger, h.flags.K8s)
-		if err != nil {
-			h.logger.Fatal().Err(err).Msg("could not bind to Kubernetes cluster")
-		}
-		h.engine = engine.NewEngine(logger, binder)
-	}
+	// Kubernetes clusters are workers as well, the binder serves all of them.
+	h.engine = engine.NewEngine(logger, standalone.NewBinder(logger, h.flags.Engine.Instance), h.flags.Engine)
+	h.health = health.NewMonitor(logger, h.flags.Health)
+	h.scaler = autoscale.NewAutoscaler(logger, h.flags.Autoscale, h.flags.Dind)
 
 	h.state.Healthy()
 	h.state.NotReady()
@@ -62,8 +78,12 @@ func NewHandler(logger log.Logger, f *Flags) *Handler {
 	h.initServer()
 	h.initDb()
 	h.initVault()
+	h.initAuth()
 	h.initScheduler()
 	h.initDocker()
+	h.initSSH()
+	h.initAgent()
+	h.initCluster()
 
 	return h
 }
@@ -76,6 +96,8 @@ func (h *Handler) Run() {
 		}
 	}()
 	go h.engine.Run()
+	go h.health.Run()
+	go h.scaler.Run()
 
 	h.state.Ready()
 
@@ -92,6 +114,8 @@ func (h *Handler) Shutdown() {
 
 	println()
 
+	<-h.scaler.Shutdown()
+	<-h.health.Shutdown()
 	<-h.engine.Shutdown()
 
 	h.logger.Info().Msg("shutting down gracefully, press Ctrl+C again to force")
@@ -102,6 +126,14 @@ func (h *Handler) Shutdown() {
 		h.logger.Error().Err(err).Msg("docker manager shutdown with error")
 	}
 
+	if err := ssh.Shutdown(); err != nil {
+		h.logger.Error().Err(err).Msg("ssh pool shutdown with error")
+	}
+
+	if err := agent.Shutdown(); err != nil {
+		h.logger.Error().Err(err).Msg("agent hub shutdown with error")
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := h.srv.Shutdown(ctx); err != nil {
@@ -119,13 +151,24 @@ func (h *Handler) initServer() {
 
 	rg := r.Group("/api")
 	router.InitProbeRouter(rg, h.state)
-	router.InitWorkerRouter(rg)
-	projectRg := router.InitProjectRouter(rg)
+	router.InitAuthRouter(rg)
+	router.InitAgentRouter(rg)
+
+	authRg := rg.Group("", auth.Authenticate())
+	router.InitTokenRouter(authRg)
+	router.InitUserRouter(authRg)
+	router.InitTeamRouter(authRg)
+	router.InitWorkerRouter(authRg)
+	router.InitPoolRouter(authRg)
+	router.InitAgentTokenRouter(authRg)
+	projectRg := router.InitProjectRouter(authRg)
 	pipelineRg := router.InitPipelineRouter(projectRg)
 	router.InitBuildRouter(pipelineRg)
-	router.InitSecretRouter(rg, projectRg, pipelineRg)
-	router.InitVariableRouter(rg, projectRg, pipelineRg)
-	router.InitQueueRouter(rg)
+	router.InitSecretRouter(authRg, projectRg, pipelineRg)
+	router.InitVariableRouter(authRg, projectRg, pipelineRg)
+	router.InitRoleBindingRouter(authRg, projectRg)
+	router.InitQueueRouter(authRg)
+	router.InitAuditRouter(authRg)
 
 	docs.SwaggerInfo.Title = "ccli - CI/CD CLI Application"
 	docs.SwaggerInfo.BasePath = "/api"
@@ -145,6 +188,15 @@ func (h *Handler) initVault() {
 	h.logger.Info().Msg("successfully connected to the vault")
 }
 
+func (h *Handler) initAuth() {
+	if err := auth.Init(h.flags.Auth); err != nil {
+		h.logger.Fatal().Err(err).Msg("error while initializing authentication")
+	}
+	if !h.flags.Auth.Enabled {
+		h.logger.Warn().Msg("authentication is disabled, API is open to everyone")
+	}
+}
+
 func (h *Handler) initDb() {
 	if err := db.Init(h.flags.DbUrl, log.Gorm(h.logger)); err != nil {
 		h.logger.Fatal().Err(err).Msg("error while connecting to the db")
@@ -158,4 +210,53 @@ func (h *Handler) initScheduler() {
 
 func (h *Handler) initDocker() {
 	docker.Init()
+	if err := h.scaler.Restore(); err != nil {
+		h.logger.Error().Err(err).Msg("could not restore ephemeral workers")
+	}
+}
+
+func (h *Handler) initSSH() {
+	ssh.Init()
+}
+
+func (h *Handler) initAgent() {
+	agent.Init()
+}
+
+// initCluster registers the cluster given by flags as worker, keeping it in
+// sync with the flags on every start.
+func (h *Handler) initCluster() {
+	if h.flags.K8s == nil {
+		return
+	}
+	input := model.WorkerInput{}
+	if h.flags.K8s.Mode == "outer" {
+		kubeconfig, err := kubernetes.ReadKubeconfig(h.flags.K8sKubeconfig)
+		if err != nil {
+			h.logger.Fatal().Err(err).Msg("could not read kubeconfig")
+		}
+		input.Kubeconfig = string(kubeconfig)
+	}
+
+	worker := model.Worker{Name: ClusterWorker}
+	err := db.Get().Where(&worker).First(&worker).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		worker = model.Worker{
+			Name:     ClusterWorker,
+			System:   "linux",
+			Type:     model.WorkerKubernetes,
+			Capacity: h.flags.K8sCapacity,
+			Cluster:  *h.flags.K8s,
+		}
+		err = db.Get().InstanceSet("input", input).Create(&worker).Error
+	} else if err == nil {
+		prev := worker
+		worker.Capacity = h.flags.K8sCapacity
+		worker.Cluster = *h.flags.K8s
+		err = db.Get().InstanceSet("prev", prev).InstanceSet("input", input).Select("capacity", "cluster").Updates(&worker).Error
+	}
+	if err != nil {
+		h.logger.Fatal().Err(err).Msg("could not register Kubernetes cluster as worker")
+	}
+	h.logger.Info().Str("namespace", h.flags.K8s.Namespace).Msg("Kubernetes cluster registered as worker [" + ClusterWorker + "]")
 }
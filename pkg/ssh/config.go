@@ -2,15 +2,21 @@ package ssh
 
 import "golang.org/x/crypto/ssh"
 
-func NewConfig(user string, privateKey string) (ssh.ClientConfig, error) {
+func NewConfig(user, privateKey, hostKey string) (ssh.ClientConfig, error) {
 	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
 	if err != nil {
 		return ssh.ClientConfig{}, err
 	}
 
+	callback, err := hostKeyCallback(hostKey)
+	if err != nil {
+		return ssh.ClientConfig{}, err
+	}
+
 	return ssh.ClientConfig{
 		User:            user,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: callback,
 		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		Timeout:         dialTimeout,
 	}, nil
 }
@@ -1,20 +1,60 @@
 package ssh
 
-import "golang.org/x/crypto/ssh"
+import (
+	"errors"
+	"net"
 
-func NewConnection(user, addr string, privateKey string) (*ssh.Client, error) {
-	cfg, err := NewConfig(user, privateKey)
+	"golang.org/x/crypto/ssh"
+)
+
+func NewConnection(user, addr, privateKey, hostKey string) (*ssh.Client, error) {
+	cfg, err := NewConfig(user, privateKey, hostKey)
+	if err != nil {
+		return nil, err
+	}
+	// ssh.Dial flattens handshake errors into text, so the mismatch is
+	// remembered by the callback instead of recovered with errors.As.
+	var mismatch *HostKeyMismatchError
+	callback := cfg.HostKeyCallback
+	cfg.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		errors.As(err, &mismatch)
+		return err
+	}
+	conn, err := ssh.Dial("tcp", addr, &cfg)
 	if err != nil {
+		if mismatch != nil {
+			return nil, mismatch
+		}
 		return nil, err
 	}
-	return ssh.Dial("tcp", addr, &cfg)
+	return conn, nil
 }
 
-func CheckConnection(user, addr string, privateKey string) error {
-	conn, err := NewConnection(user, addr, privateKey)
+// CheckConnection verifies the worker is reachable, reusing the pooled
+// connection when the pool is running.
+func CheckConnection(user, addr, privateKey, hostKey string) error {
+	if pool == nil {
+		conn, err := NewConnection(user, addr, privateKey, hostKey)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	c, err := pool.acquire(user, addr, privateKey, hostKey)
+	if err != nil {
+		return err
+	}
+	if err := ping(c.client); err == nil {
+		pool.release(c)
+		return nil
+	}
+	pool.fail(c)
+	c, err = pool.acquire(user, addr, privateKey, hostKey)
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
-	return nil
+	defer pool.release(c)
+	return ping(c.client)
 }
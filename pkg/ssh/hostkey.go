@@ -0,0 +1,77 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	ErrHostKeyMissing = errors.New("host key of the worker is not known")
+	ErrHostKeyScan    = errors.New("could not retrieve host key")
+)
+
+type HostKeyMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("host key mismatch: expected [%s], got [%s]", e.Expected, e.Actual)
+}
+
+// ScanHostKey performs the handshake with the host only to learn its key, the
+// key is returned in authorized_keys format together with its fingerprint.
+func ScanHostKey(addr string) (string, string, error) {
+	var key ssh.PublicKey
+	cfg := ssh.ClientConfig{
+		User:    "ccli",
+		Timeout: dialTimeout,
+		HostKeyCallback: func(_ string, _ net.Addr, k ssh.PublicKey) error {
+			key = k
+			return nil
+		},
+	}
+	conn, err := ssh.Dial("tcp", addr, &cfg)
+	if err == nil {
+		conn.Close()
+	}
+	if key == nil {
+		return "", "", fmt.Errorf("%w: %v", ErrHostKeyScan, err)
+	}
+	return MarshalHostKey(key), ssh.FingerprintSHA256(key), nil
+}
+
+func MarshalHostKey(key ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
+
+func Fingerprint(hostKey string) (string, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+	if err != nil {
+		return "", err
+	}
+	return ssh.FingerprintSHA256(key), nil
+}
+
+func hostKeyCallback(hostKey string) (ssh.HostKeyCallback, error) {
+	if hostKey == "" {
+		return nil, ErrHostKeyMissing
+	}
+	expected, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+	if err != nil {
+		return nil, err
+	}
+	return func(_ string, _ net.Addr, key ssh.PublicKey) error {
+		if string(key.Marshal()) != string(expected.Marshal()) {
+			return &HostKeyMismatchError{
+				Expected: ssh.FingerprintSHA256(expected),
+				Actual:   ssh.FingerprintSHA256(key),
+			}
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,228 @@
+package ssh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	dialTimeout       = 10 * time.Second
+	keepaliveInterval = 30 * time.Second
+	keepaliveTimeout  = 10 * time.Second
+)
+
+type Pool struct {
+	mu      sync.Mutex
+	clients map[string]*pooledClient
+	dialing map[string]*pendingDial
+}
+
+// pooledClient is shared by all sessions on the worker. Dropped from the
+// pool (stale) it is closed once the last session releases it, so failures
+// of one build do not kill sessions of the others.
+type pooledClient struct {
+	key         string
+	client      *ssh.Client
+	credentials string
+	done        chan any
+	refs        int
+	stale       bool
+}
+
+// pendingDial lets concurrent acquires of the same worker wait for a single
+// dial without holding the pool lock.
+type pendingDial struct {
+	credentials string
+	done        chan any
+	err         error
+}
+
+var pool *Pool
+
+func GetPool() *Pool {
+	if pool == nil {
+		panic("ssh pool is not initialized")
+	}
+	return pool
+}
+
+func Init() error {
+	if pool != nil {
+		panic("ssh pool is already initialized")
+	}
+
+	pool = &Pool{
+		clients: map[string]*pooledClient{},
+		dialing: map[string]*pendingDial{},
+	}
+
+	return nil
+}
+
+// acquire returns the long-lived client for the worker, dialing a new one if
+// there is none or the credentials changed since it was established. The
+// client has to be released once it is no longer used.
+func (p *Pool) acquire(user, addr, privateKey, hostKey string) (*pooledClient, error) {
+	key := user + "@" + addr
+	credentials := hash(privateKey, hostKey)
+
+	for {
+		p.mu.Lock()
+		if c, ok := p.clients[key]; ok {
+			if c.credentials == credentials {
+				c.refs++
+				p.mu.Unlock()
+				return c, nil
+			}
+			p.discard(c)
+		}
+		if d, ok := p.dialing[key]; ok {
+			p.mu.Unlock()
+			<-d.done
+			if d.credentials == credentials && d.err != nil {
+				return nil, d.err
+			}
+			continue
+		}
+		d := &pendingDial{credentials: credentials, done: make(chan any)}
+		p.dialing[key] = d
+		p.mu.Unlock()
+
+		client, err := NewConnection(user, addr, privateKey, hostKey)
+
+		p.mu.Lock()
+		delete(p.dialing, key)
+		var c *pooledClient
+		if err == nil {
+			c = &pooledClient{key: key, client: client, credentials: credentials, done: make(chan any), refs: 1}
+			p.clients[key] = c
+			go p.keepalive(c)
+		}
+		d.err = err
+		close(d.done)
+		p.mu.Unlock()
+
+		return c, err
+	}
+}
+
+// release gives the client back, closing it if it was discarded meanwhile
+// and this was its last user.
+func (p *Pool) release(c *pooledClient) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c.refs--
+	if c.stale && c.refs == 0 {
+		c.client.Close()
+	}
+}
+
+// fail discards the client after its failure and releases it, so the next
+// acquire reconnects.
+func (p *Pool) fail(c *pooledClient) {
+	p.mu.Lock()
+	p.discard(c)
+	p.mu.Unlock()
+	p.release(c)
+}
+
+// discard drops the client from the pool, it is closed right away only when
+// no session uses it. Must be called with the lock held.
+func (p *Pool) discard(c *pooledClient) {
+	if p.clients[c.key] == c {
+		delete(p.clients, c.key)
+	}
+	if c.stale {
+		return
+	}
+	c.stale = true
+	close(c.done)
+	if c.refs == 0 {
+		c.client.Close()
+	}
+}
+
+func (p *Pool) keepalive(c *pooledClient) {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := ping(c.client); err != nil {
+				p.mu.Lock()
+				p.discard(c)
+				p.mu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+func (p *Pool) newSession(user, addr, privateKey, hostKey string) (*pooledClient, *ssh.Session, error) {
+	for attempt := 0; ; attempt++ {
+		c, err := p.acquire(user, addr, privateKey, hostKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		session, err := c.client.NewSession()
+		if err == nil {
+			return c, session, nil
+		}
+		p.fail(c)
+		if attempt == 1 {
+			return nil, nil, err
+		}
+	}
+}
+
+func Shutdown() error {
+	p := GetPool()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	errMsg := []string{}
+	for key, c := range p.clients {
+		delete(p.clients, key)
+		c.stale = true
+		close(c.done)
+		if err := c.client.Close(); err != nil {
+			errMsg = append(errMsg, err.Error())
+		}
+	}
+
+	if len(errMsg) != 0 {
+		return fmt.Errorf("pool close: %d clients closed with error\n%s", len(errMsg), strings.Join(errMsg, "\n"))
+	}
+	return nil
+}
+
+// ping sends a keepalive request, failing if the host does not answer in time.
+func ping(client *ssh.Client) error {
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(keepaliveTimeout):
+		return errors.New("keepalive timed out")
+	}
+}
+
+func hash(values ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
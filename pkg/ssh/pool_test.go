@@ -0,0 +1,177 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer accepts any client key and keeps sessions open until the
+// client closes them, requests on sessions are acknowledged.
+func testServer(t *testing.T) (addr, hostKey string) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) { return nil, nil },
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn, config)
+		}
+	}()
+	return listener.Addr().String(), MarshalHostKey(signer.PublicKey())
+}
+
+func serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer channel.Close()
+			for req := range requests {
+				req.Reply(true, nil)
+			}
+		}()
+	}
+}
+
+func privateKey(t *testing.T) string {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(block))
+}
+
+func testPool() *Pool {
+	return &Pool{clients: map[string]*pooledClient{}, dialing: map[string]*pendingDial{}}
+}
+
+// usable reports whether the session still reaches the server.
+func usable(session *ssh.Session) bool {
+	ok, err := session.SendRequest("env", true, ssh.Marshal(struct{ Name, Value string }{"A", "B"}))
+	return err == nil && ok
+}
+
+// closed reports whether the client connection was closed.
+func closed(c *pooledClient) bool {
+	done := make(chan any)
+	go func() {
+		c.client.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func TestPoolSharesClientBetweenSessions(t *testing.T) {
+	addr, hostKey := testServer(t)
+	key := privateKey(t)
+	p := testPool()
+
+	first, firstSession, err := p.newSession("ci", addr, key, hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, secondSession, err := p.newSession("ci", addr, key, hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second || first.refs != 2 {
+		t.Fatalf("sessions got different clients or wrong refs (%d)", first.refs)
+	}
+
+	// Failure in one build drops the client but keeps the other session.
+	firstSession.Close()
+	p.fail(first)
+	if !usable(secondSession) {
+		t.Error("session of other build closed with the failed client")
+	}
+
+	third, thirdSession, err := p.newSession("ci", addr, key, hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == first {
+		t.Error("discarded client acquired again")
+	}
+
+	secondSession.Close()
+	p.release(second)
+	if !closed(first) {
+		t.Error("discarded client not closed after its last session")
+	}
+	if !usable(thirdSession) {
+		t.Error("new client closed with the discarded one")
+	}
+	thirdSession.Close()
+	p.release(third)
+	if closed(third) {
+		t.Error("pooled client closed while in pool")
+	}
+}
+
+func TestPoolCredentialChangeKeepsRunningSessions(t *testing.T) {
+	addr, hostKey := testServer(t)
+	p := testPool()
+
+	old, session, err := p.newSession("ci", addr, privateKey(t), hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renewed, err := p.acquire("ci", addr, privateKey(t), hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed == old {
+		t.Fatal("client with old credentials acquired")
+	}
+	if !usable(session) {
+		t.Error("session closed on credential change")
+	}
+	session.Close()
+	p.release(old)
+	if !closed(old) {
+		t.Error("client with old credentials not closed after its last session")
+	}
+	p.release(renewed)
+}
@@ -1,39 +1,45 @@
 package ssh
 
-import "github.com/gg-mike/ccli/pkg/runner"
+import (
+	"sync"
 
-func NewRunner(username, address, privateKey string) (*runner.Runner, error) {
-	var err error
+	"github.com/gg-mike/ccli/pkg/runner"
+)
 
-	conn, err := NewConnection(username, address, privateKey)
-	if err != nil {
-		return &runner.Runner{}, err
-	}
+func NewRunner(username, address, privateKey, hostKey string) (*runner.Runner, error) {
+	var err error
 
-	session, err := conn.NewSession()
+	client, session, err := GetPool().newSession(username, address, privateKey, hostKey)
 	if err != nil {
 		return &runner.Runner{}, err
 	}
 
 	w, err := session.StdinPipe()
 	if err != nil {
+		session.Close()
+		GetPool().release(client)
 		return &runner.Runner{}, err
 	}
 	r, err := session.StdoutPipe()
 	if err != nil {
+		session.Close()
+		GetPool().release(client)
 		return &runner.Runner{}, err
 	}
 
 	if err = session.Shell(); err != nil {
+		session.Close()
+		GetPool().release(client)
 		return &runner.Runner{}, err
 	}
 
 	_runner := runner.NewRunner(w, r)
+	var once sync.Once
 	_runner.OnShutdown = func() error {
-		if err := session.Close(); err != nil {
-			return err
-		}
-		return conn.Close()
+		err := session.Close()
+		// The connection stays open for other builds on the worker.
+		once.Do(func() { GetPool().release(client) })
+		return err
 	}
 
 	return _runner, nil